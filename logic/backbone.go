@@ -0,0 +1,80 @@
+package logic
+
+import (
+	"sort"
+)
+
+// Backbone returns the literals which are true in every model of cs,
+// sorted by variable. The second return value is false if cs has no
+// model at all.
+//
+// Every candidate literal of the first model is tested by solving
+// under its negation. Models found along the way rule out all
+// candidates they disagree with, as do their rotations: a literal
+// which is not the only true literal of any clause can be flipped
+// without leaving the model space.
+func Backbone(cs *ClauseSet) ([]Lit, bool) {
+	s := NewSolverFromClauses(cs)
+	if !s.Solve() {
+		return nil, false
+	}
+
+	candidates := make([]Lit, cs.NumVars+1)
+	for v := 1; v <= cs.NumVars; v++ {
+		if s.Model()[v] {
+			candidates[v] = Lit(v)
+		} else {
+			candidates[v] = Lit(-v)
+		}
+	}
+	filterCandidates(cs, candidates, s.Model())
+
+	r := make([]Lit, 0)
+	for v := 1; v <= cs.NumVars; v++ {
+		l := candidates[v]
+		if l == 0 {
+			continue
+		}
+		if s.Solve(-l) {
+			filterCandidates(cs, candidates, s.Model())
+			continue
+		}
+		r = append(r, l)
+		s.AddClause(l)
+	}
+	sort.Sort(litSlice(r))
+	return r, true
+}
+
+// Removes every candidate which disagrees with model or can be
+// rotated in it.
+func filterCandidates(cs *ClauseSet, candidates []Lit, model []bool) {
+	for v := 1; v < len(candidates); v++ {
+		if candidates[v] != 0 && model[v] != (candidates[v] > 0) {
+			candidates[v] = 0
+		}
+	}
+	// Variables which are the only true literal of a clause cannot
+	// be flipped.
+	critical := make([]bool, len(candidates))
+	for _, c := range cs.Clauses {
+		unique := Lit(0)
+		for _, l := range c {
+			if model[l.Var()] == (l > 0) {
+				if unique != 0 && unique != l {
+					unique = 0
+					break
+				}
+				unique = l
+			}
+		}
+		if unique != 0 {
+			critical[unique.Var()] = true
+		}
+	}
+	for v := 1; v < len(candidates); v++ {
+		if !critical[v] {
+			candidates[v] = 0
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package logic
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestBackbone(t *testing.T) {
+	// a ^ (a => b) ^ (c v d)
+	l := NewOperation(AND, NewLeaf("a"),
+		NewOperation(IF, NewLeaf("a"), NewLeaf("b")),
+		NewOperation(OR, NewLeaf("c"), NewLeaf("d")))
+	cs := Clauses(l)
+	bb, ok := Backbone(cs)
+	if !ok {
+		t.Fatalf("%s is satisfiable", l)
+	}
+	if len(bb) != 2 || bb[0] != cs.Lit("a", true) || bb[1] != cs.Lit("b", true) {
+		t.Fatalf("Backbone(%s) returned %v", l, bb)
+	}
+
+	_, ok = Backbone(Clauses(NewOperation(AND, NewLeaf("a"), NewOperation(NOT, NewLeaf("a")))))
+	if ok {
+		t.Fatalf("Backbone of unsatisfiable formula")
+	}
+}
+
+func TestBackboneRandom(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	for i := 0; i < 200; i++ {
+		cs := randomClauseSet(rng, 8, 20+rng.Intn(20), 3)
+		fixed := make([]int, cs.NumVars+1)
+		count := 0
+		allModels(cs, func(model []bool) {
+			count++
+			for v := 1; v <= cs.NumVars; v++ {
+				if model[v] {
+					fixed[v]++
+				}
+			}
+		})
+		bb, ok := Backbone(cs)
+		if ok != (count > 0) {
+			t.Fatalf("Backbone returned %v for %s", ok, cs.DIMACS())
+		}
+		expected := []Lit{}
+		for v := 1; v <= cs.NumVars && count > 0; v++ {
+			if fixed[v] == count {
+				expected = append(expected, Lit(v))
+			} else if fixed[v] == 0 {
+				expected = append(expected, Lit(-v))
+			}
+		}
+		if len(bb) != len(expected) {
+			t.Fatalf("Backbone(%s) returned %v, expected %v", cs.DIMACS(), bb, expected)
+		}
+		for i := range bb {
+			if bb[i] != expected[i] {
+				t.Fatalf("Backbone(%s) returned %v, expected %v", cs.DIMACS(), bb, expected)
+			}
+		}
+	}
+}
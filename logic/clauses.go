@@ -0,0 +1,176 @@
+package logic
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+)
+
+// A literal in DIMACS notation: the index of a variable,
+// negated if the literal is negative.
+type Lit int
+
+func (l Lit) Var() int {
+	if l < 0 {
+		return int(-l)
+	}
+	return int(l)
+}
+
+func (l Lit) Neg() Lit {
+	return -l
+}
+
+// Index into per-literal arrays: 2*var for positive, 2*var+1 for
+// negative literals.
+func (l Lit) code() int {
+	if l < 0 {
+		return 2*int(-l) + 1
+	}
+	return 2 * int(l)
+}
+
+type Clause []Lit
+
+func (c Clause) String() string {
+	var b bytes.Buffer
+	for _, l := range c {
+		b.WriteString(strconv.Itoa(int(l)))
+		b.WriteByte(' ')
+	}
+	b.WriteString("0")
+	return b.String()
+}
+
+// ClauseSet is a CNF in DIMACS numbering together with the
+// mapping between variable indices and leaf names.
+type ClauseSet struct {
+	NumVars int
+	Clauses []Clause
+	Index   map[string]int
+	// Names[i] is the leaf name of variable i. Names[0] is unused.
+	Names []string
+}
+
+func NewClauseSet() *ClauseSet {
+	return &ClauseSet{
+		Index: make(map[string]int),
+		Names: []string{""},
+	}
+}
+
+// Clauses converts n to CNF and numbers the leafs in order of
+// their first appearance, exactly like FormatSAT does.
+func Clauses(n Node) *ClauseSet {
+	cs := NewClauseSet()
+	cnf := CNF(n).(*Operation)
+	for _, op := range cnf.Operands {
+		or := op.(*Operation)
+		c := make(Clause, 0, len(or.Operands))
+		for _, x := range or.Operands {
+			c = append(c, cs.literal(x))
+		}
+		cs.Clauses = append(cs.Clauses, c)
+	}
+	return cs
+}
+
+func (cs *ClauseSet) literal(n Node) Lit {
+	switch x := n.(type) {
+	case Leaf:
+		return Lit(cs.Var(string(x)))
+	case *Operation:
+		if x.Operator == NOT {
+			return -cs.literal(x.Operands[0])
+		}
+	}
+	panic("This is not possibe o_O")
+}
+
+// Var returns the variable index of the given leaf name,
+// allocating a new variable if the name is unknown.
+func (cs *ClauseSet) Var(name string) int {
+	if idx, ok := cs.Index[name]; ok {
+		return idx
+	}
+	cs.NumVars++
+	cs.Index[name] = cs.NumVars
+	cs.Names = append(cs.Names, name)
+	return cs.NumVars
+}
+
+// Lit returns the literal for the leaf name with the given value.
+// Unknown names yield 0.
+func (cs *ClauseSet) Lit(name string, value bool) Lit {
+	idx, ok := cs.Index[name]
+	if !ok {
+		return 0
+	}
+	if !value {
+		return Lit(-idx)
+	}
+	return Lit(idx)
+}
+
+func (cs *ClauseSet) AddClause(lits ...Lit) {
+	for _, l := range lits {
+		if l.Var() > cs.NumVars {
+			cs.grow(l.Var())
+		}
+	}
+	cs.Clauses = append(cs.Clauses, Clause(lits))
+}
+
+func (cs *ClauseSet) grow(nvars int) {
+	for cs.NumVars < nvars {
+		cs.NumVars++
+		cs.Names = append(cs.Names, "")
+	}
+}
+
+// Configuration translates a model (indexed by variable) back to
+// leaf names.
+func (cs *ClauseSet) Configuration(model []bool) Configuration {
+	config := make(Configuration)
+	for name, idx := range cs.Index {
+		if idx < len(model) {
+			config[name] = model[idx]
+		}
+	}
+	return config
+}
+
+// Satisfies reports whether model (indexed by variable) satisfies
+// every clause.
+func (cs *ClauseSet) Satisfies(model []bool) bool {
+	for _, c := range cs.Clauses {
+		if !c.satisfiedBy(model) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c Clause) satisfiedBy(model []bool) bool {
+	for _, l := range c {
+		if model[l.Var()] == (l > 0) {
+			return true
+		}
+	}
+	return false
+}
+
+// DIMACS returns the clause set in the format FormatSAT produces.
+func (cs *ClauseSet) DIMACS() string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "p cnf %d %d\n", cs.NumVars, len(cs.Clauses))
+	for _, c := range cs.Clauses {
+		sep := ""
+		for _, l := range c {
+			b.WriteString(sep + strconv.Itoa(int(l)))
+			sep = " "
+		}
+		b.WriteString(" 0 \n")
+	}
+	return b.String()
+}
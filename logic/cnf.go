@@ -10,34 +10,30 @@ func operandMap(parent *Operation, f func(Node) Node) *Operation {
 	return r
 }
 
-// Reduces expressions to using only AND, OR and NOT
+// AND and OR without operands are the constants true and false.
+func isConstant(x *Operation) bool {
+	return len(x.Operands) == 0 && (x.Operator == AND || x.Operator == OR)
+}
+
+// Reduces expressions to using only AND, OR and NOT by applying
+// the rewrites of the other operators. AND and OR without operands
+// are kept as the constants true and false. It panics on unknown
+// operators.
 func Simplify(n Node) Node {
 	if x, ok := n.(*Operation); ok {
+		if isConstant(x) {
+			return x
+		}
 		if len(x.Operands) == 0 {
 			return nil
 		}
-		if x.Operator != NOT && len(x.Operands) == 1 {
+		if (x.Operator == AND || x.Operator == OR) && len(x.Operands) == 1 {
 			return Simplify(x.Operands[0])
 		}
 
-		if x.Operator == IF {
-			op1 := x.Operands[0]
-			op2 := x.Operands[1]
-			if len(x.Operands) > 2 {
-				op2 = Simplify(op2)
-			}
-			// a -> b <=> (!a v b)
-			return NewOperation(OR, NewOperation(NOT, op1), op2)
-		}
-
-		if x.Operator == IFF {
-			op1 := x.Operands[0]
-			op2 := x.Operands[1]
-			if len(x.Operands) > 2 {
-				op2 = Simplify(op2)
-			}
-			// a <-> b <=> (a -> b) ^ (b -> a)
-			return Simplify(NewOperation(AND, NewOperation(IF, op1, op2), NewOperation(IF, op2, op1)))
+		op := mustOperatorOf(x)
+		if op.Rewrite != nil {
+			return Simplify(op.Rewrite(x.Operands))
 		}
 		return operandMap(x, Simplify)
 	}
@@ -49,6 +45,9 @@ func Simplify(n Node) Node {
 func DeMorgan(n Node) Node {
 	n = Simplify(n)
 	if x, ok := n.(*Operation); ok {
+		if isConstant(x) {
+			return x
+		}
 		if len(x.Operands) == 0 {
 			return nil
 		}
@@ -80,7 +79,7 @@ func DeMorgan(n Node) Node {
 }
 
 // Converts expression to CNF
-// Implies DeMorgan()
+// Implies DeMorgan(). True becomes no clause, false the empty clause.
 //
 // Also: This function is ugly as shit. Kill it with fire.
 func CNF(n Node) Node {
@@ -95,6 +94,15 @@ func CNF(n Node) Node {
 		switch x.Operator {
 		case OR:
 			and := NewOperation(AND)
+			if len(x.Operands) == 0 {
+				return NewOperation(AND, NewOperation(OR))
+			}
+			for _, minterm := range x.Operands {
+				// A true operand makes the disjunction true
+				if len(minterm.(*Operation).Operands) == 0 {
+					return and
+				}
+			}
 			count := make([]int, len(x.Operands))
 			maxcount := make([]int, len(x.Operands))
 			minterms := x.Operands
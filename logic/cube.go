@@ -0,0 +1,317 @@
+package logic
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Number of variables evaluated by the lookahead at every node of
+// the cube tree. The most frequent variables are taken.
+const lookaheadCandidates = 200
+
+// CubeSplit is a decision tree over the variables of a clause set.
+// Its leafs are either cubes left to be solved or paths refuted by
+// unit propagation during the lookahead.
+type CubeSplit struct {
+	Cubes   [][]Lit
+	Refuted [][]Lit
+}
+
+// Cubes splits cs into at most n cubes by lookahead: every node of
+// the tree branches on the variable whose two assignments propagate
+// the most (product of newly assigned variables). A failed literal
+// becomes a refuted leaf and the node continues on its negation.
+func Cubes(cs *ClauseSet, n int) *CubeSplit {
+	s := NewSolverFromClauses(cs)
+	split := &CubeSplit{}
+	if !s.ok {
+		split.Refuted = append(split.Refuted, []Lit{})
+		return split
+	}
+	occurrences := make([]int, cs.NumVars+1)
+	for _, c := range cs.Clauses {
+		for _, l := range c {
+			occurrences[l.Var()]++
+		}
+	}
+
+	// Leafs which may still be split, shortest first.
+	queue := [][]Lit{[]Lit{}}
+	for len(queue) > 0 && len(queue)+len(split.Cubes) < n {
+		path := queue[0]
+		queue = queue[1:]
+		if !s.assume(path) {
+			split.Refuted = append(split.Refuted, path)
+			continue
+		}
+		for {
+			v, failed := s.lookahead(occurrences)
+			if v == 0 {
+				// Everything is assigned, nothing left to split.
+				split.Cubes = append(split.Cubes, path)
+				break
+			}
+			if failed == 0 {
+				queue = append(queue, extend(path, Lit(v)), extend(path, Lit(-v)))
+				break
+			}
+			split.Refuted = append(split.Refuted, extend(path, failed))
+			path = extend(path, -failed)
+			if !s.assume(path) {
+				split.Refuted = append(split.Refuted, path)
+				break
+			}
+		}
+	}
+	s.cancelUntil(0)
+	split.Cubes = append(split.Cubes, queue...)
+	return split
+}
+
+func extend(path []Lit, l Lit) []Lit {
+	r := make([]Lit, len(path), len(path)+1)
+	copy(r, path)
+	return append(r, l)
+}
+
+// Assigns path on level 1 and propagates. Returns false on conflict.
+func (s *Solver) assume(path []Lit) bool {
+	s.cancelUntil(0)
+	s.trailLim = append(s.trailLim, len(s.trail))
+	for _, l := range path {
+		switch s.value(l) {
+		case lFalse:
+			return false
+		case lUndef:
+			s.enqueue(l, nil)
+		}
+	}
+	return s.propagate() == nil
+}
+
+// Returns the best variable to branch on, or a failed literal
+// (with its variable).
+func (s *Solver) lookahead(occurrences []int) (int, Lit) {
+	vars := make([]int, 0)
+	for v := 1; v <= s.nvars; v++ {
+		if s.assigns[v] == lUndef {
+			vars = append(vars, v)
+		}
+	}
+	if len(vars) > lookaheadCandidates {
+		sort.SliceStable(vars, func(i, j int) bool {
+			return occurrences[vars[i]] > occurrences[vars[j]]
+		})
+		vars = vars[:lookaheadCandidates]
+	}
+	best, bestScore := 0, -1
+	for _, v := range vars {
+		pos, ok := s.probe(Lit(v))
+		if !ok {
+			return v, Lit(v)
+		}
+		neg, ok := s.probe(Lit(-v))
+		if !ok {
+			return v, Lit(-v)
+		}
+		if score := (pos + 1) * (neg + 1); score > bestScore {
+			best, bestScore = v, score
+		}
+	}
+	return best, 0
+}
+
+// Assigns l on a new level and returns the number of implied
+// assignments. The second return value is false on conflict.
+func (s *Solver) probe(l Lit) (int, bool) {
+	level := s.decisionLevel()
+	start := len(s.trail)
+	s.trailLim = append(s.trailLim, start)
+	s.enqueue(l, nil)
+	ok := s.propagate() == nil
+	n := len(s.trail) - start
+	s.cancelUntil(level)
+	return n, ok
+}
+
+// WriteICNF writes cs and the cubes of split in the incremental
+// CNF format: the clauses followed by one "a" line per cube.
+func WriteICNF(w io.Writer, cs *ClauseSet, split *CubeSplit) error {
+	b := bufio.NewWriter(w)
+	fmt.Fprintf(b, "p inccnf\n")
+	for _, c := range cs.Clauses {
+		fmt.Fprintf(b, "%s\n", c)
+	}
+	for _, cube := range split.Cubes {
+		fmt.Fprintf(b, "a %s\n", Clause(cube))
+	}
+	return b.Flush()
+}
+
+// CubeDIMACS returns cs with the literals of cube added as unit
+// clauses.
+func CubeDIMACS(cs *ClauseSet, cube []Lit) string {
+	r := &ClauseSet{
+		NumVars: cs.NumVars,
+		Clauses: append([]Clause(nil), cs.Clauses...),
+	}
+	for _, l := range cube {
+		r.Clauses = append(r.Clauses, Clause{l})
+	}
+	return r.DIMACS()
+}
+
+// CubeResult is the outcome of solving cs together with one cube.
+type CubeResult struct {
+	Satisfiable bool
+	Model       []bool
+	// DRAT proof for the clause set written by CubeDIMACS, if
+	// unsatisfiable.
+	Proof []byte
+}
+
+// SolveCube solves cs under cube with the built-in solver. If proof
+// is set, unsatisfiable results come with a DRAT proof.
+func SolveCube(cs *ClauseSet, cube []Lit, proof bool) CubeResult {
+	s := NewSolver(cs.NumVars)
+	var b bytes.Buffer
+	if proof {
+		s.Proof = &b
+	}
+	for _, c := range cs.Clauses {
+		s.AddClause(c...)
+	}
+	for _, l := range cube {
+		s.AddClause(l)
+	}
+	if s.Solve() {
+		return CubeResult{Satisfiable: true, Model: s.Model()}
+	}
+	return CubeResult{Proof: b.Bytes()}
+}
+
+// ParseSolverOutput reads the result of an external solver in the
+// usual competition format ("s SATISFIABLE" and "v" lines).
+func ParseSolverOutput(r io.Reader, nvars int) (CubeResult, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1<<30)
+	status := ""
+	model := make([]bool, nvars+1)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		switch {
+		case strings.HasPrefix(line, "s "):
+			status = strings.TrimSpace(line[2:])
+		case strings.HasPrefix(line, "v "):
+			for _, field := range strings.Fields(line[2:]) {
+				v, e := strconv.Atoi(field)
+				if e != nil {
+					return CubeResult{}, fmt.Errorf("Invalid literal %s in solver output", field)
+				}
+				if v > 0 && v <= nvars {
+					model[v] = true
+				}
+			}
+		}
+	}
+	if e := sc.Err(); e != nil {
+		return CubeResult{}, e
+	}
+	switch status {
+	case "SATISFIABLE":
+		return CubeResult{Satisfiable: true, Model: model}, nil
+	case "UNSATISFIABLE":
+		return CubeResult{}, nil
+	}
+	return CubeResult{}, fmt.Errorf("Solver output has no result")
+}
+
+// MergeCubeResults combines the results of all cubes of split (in
+// order) into the answer for cs. A model of any cube is a model of
+// cs. If every cube is unsatisfiable and proof is set, a DRAT proof
+// for cs is written to it: every lemma of the cube proofs is
+// weakened by the negated cube, then the negations of the refuted
+// paths and of all inner nodes of the tree are derived bottom-up,
+// ending with the empty clause. Cube proofs have to consist of RUP
+// lemmas, which is what the built-in solver produces.
+func MergeCubeResults(cs *ClauseSet, split *CubeSplit, results []CubeResult, proof io.Writer) (bool, []bool, error) {
+	if len(results) != len(split.Cubes) {
+		return false, nil, fmt.Errorf("Got %d results for %d cubes", len(results), len(split.Cubes))
+	}
+	for i, r := range results {
+		if !r.Satisfiable {
+			continue
+		}
+		if len(r.Model) <= cs.NumVars || !cs.Satisfies(r.Model) {
+			return false, nil, fmt.Errorf("Model of cube %d does not satisfy the formula", i+1)
+		}
+		return true, r.Model, nil
+	}
+	if proof == nil {
+		return false, nil, nil
+	}
+
+	b := bufio.NewWriter(proof)
+	for i, r := range results {
+		if r.Proof == nil {
+			return false, nil, fmt.Errorf("Cube %d has no proof", i+1)
+		}
+		steps, e := readDRAT(bytes.NewReader(r.Proof))
+		if e != nil {
+			return false, nil, fmt.Errorf("Proof of cube %d: %s", i+1, e)
+		}
+		cube := split.Cubes[i]
+		for _, step := range steps {
+			// Deletions could refer to clauses other cubes still need.
+			if step.delete {
+				continue
+			}
+			fmt.Fprintf(b, "%s\n", negatedCube(step.lits, cube))
+		}
+		fmt.Fprintf(b, "%s\n", negatedCube(nil, cube))
+	}
+	for _, path := range split.Refuted {
+		fmt.Fprintf(b, "%s\n", negatedCube(nil, path))
+	}
+
+	prefixes := make(map[string][]Lit)
+	leafs := append(append([][]Lit(nil), split.Cubes...), split.Refuted...)
+	for _, leaf := range leafs {
+		for l := 0; l < len(leaf); l++ {
+			prefixes[Clause(leaf[:l]).String()] = leaf[:l]
+		}
+	}
+	inner := make([][]Lit, 0, len(prefixes))
+	for _, p := range prefixes {
+		inner = append(inner, p)
+	}
+	sort.Slice(inner, func(i, j int) bool {
+		if len(inner[i]) != len(inner[j]) {
+			return len(inner[i]) > len(inner[j])
+		}
+		return Clause(inner[i]).String() < Clause(inner[j]).String()
+	})
+	for _, p := range inner {
+		fmt.Fprintf(b, "%s\n", negatedCube(nil, p))
+	}
+	// The root is the empty path. If every leaf is the root itself,
+	// the empty clause has been derived above already.
+	if len(inner) == 0 {
+		fmt.Fprintf(b, "0\n")
+	}
+	return false, nil, b.Flush()
+}
+
+// Returns lits extended by the negation of every literal of cube.
+func negatedCube(lits []Lit, cube []Lit) Clause {
+	r := append(Clause(nil), lits...)
+	for _, l := range cube {
+		r = append(r, -l)
+	}
+	return r
+}
@@ -0,0 +1,69 @@
+package logic
+
+import (
+	"bytes"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestCubesUnsatisfiable(t *testing.T) {
+	cs := pigeonhole(7, 6)
+	split := Cubes(cs, 8)
+	if len(split.Cubes) == 0 || len(split.Cubes) > 8 {
+		t.Fatalf("Got %d cubes", len(split.Cubes))
+	}
+	results := make([]CubeResult, len(split.Cubes))
+	for i, cube := range split.Cubes {
+		results[i] = SolveCube(cs, cube, true)
+	}
+	var proof bytes.Buffer
+	sat, _, e := MergeCubeResults(cs, split, results, &proof)
+	if e != nil || sat {
+		t.Fatalf("MergeCubeResults returned %v, %v", sat, e)
+	}
+	if e := CheckDRAT(cs, &proof); e != nil {
+		t.Fatalf("Merged proof rejected: %s", e)
+	}
+}
+
+func TestCubesRandom(t *testing.T) {
+	rng := rand.New(rand.NewSource(6))
+	for i := 0; i < 20; i++ {
+		cs := randomClauseSet(rng, 30, 100+rng.Intn(80), 3)
+		expected := NewSolverFromClauses(cs).Solve()
+		split := Cubes(cs, 16)
+		results := make([]CubeResult, len(split.Cubes))
+		for i, cube := range split.Cubes {
+			results[i] = SolveCube(cs, cube, true)
+		}
+		var proof bytes.Buffer
+		sat, model, e := MergeCubeResults(cs, split, results, &proof)
+		if e != nil || sat != expected {
+			t.Fatalf("MergeCubeResults returned %v, %v for %s", sat, e, cs.DIMACS())
+		}
+		if sat && !cs.Satisfies(model) {
+			t.Fatalf("Invalid model for %s", cs.DIMACS())
+		}
+		if !sat {
+			if e := CheckDRAT(cs, &proof); e != nil {
+				t.Fatalf("Merged proof rejected: %s", e)
+			}
+		}
+	}
+}
+
+func TestWriteICNF(t *testing.T) {
+	cs := NewClauseSet()
+	cs.AddClause(1, -2)
+	split := &CubeSplit{Cubes: [][]Lit{{1}, {-1, 2}}}
+	var b bytes.Buffer
+	WriteICNF(&b, cs, split)
+	if b.String() != "p inccnf\n1 -2 0\na 1 0\na -1 2 0\n" {
+		t.Fatalf("WriteICNF returned %q", b.String())
+	}
+	r, e := ParseSolverOutput(strings.NewReader("c foo\ns SATISFIABLE\nv -1 2 0\n"), 2)
+	if e != nil || !r.Satisfiable || r.Model[1] || !r.Model[2] {
+		t.Fatalf("ParseSolverOutput returned %v, %s", r, e)
+	}
+}
@@ -0,0 +1,692 @@
+package logic
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type DNNFKind int
+
+const (
+	DNNFTrue DNNFKind = iota
+	DNNFFalse
+	DNNFLit
+	DNNFAnd
+	DNNFOr
+)
+
+type DNNFNode struct {
+	Kind DNNFKind
+	// Literal of DNNFLit nodes
+	Lit Lit
+	// Variable an DNNFOr node decides on, 0 if unknown
+	Decision int
+	// Indices of the children in DNNF.Nodes, always smaller than the
+	// index of the node itself
+	Children []int
+}
+
+// DNNF is a smooth decision-DNNF: conjunctions have children on
+// disjoint variables, disjunctions have mutually exclusive children
+// on the same variables, and the root mentions every variable.
+type DNNF struct {
+	NumVars int
+	Nodes   []DNNFNode
+	Root    int
+}
+
+type dnnfCompiler struct {
+	d     *DNNF
+	cache map[string]int
+	// Node ids of literals and of free variables (v or -v)
+	lits map[Lit]int
+	free map[int]int
+}
+
+// CompileDNNF compiles cs into a smooth decision-DNNF by exhaustive
+// DPLL search with unit propagation, component decomposition and
+// caching of components.
+func CompileDNNF(cs *ClauseSet) *DNNF {
+	c := &dnnfCompiler{
+		d:     &DNNF{NumVars: cs.NumVars},
+		cache: make(map[string]int),
+		lits:  make(map[Lit]int),
+		free:  make(map[int]int),
+	}
+	clauses := normalizeClauses(cs)
+	vars := make([]int, cs.NumVars)
+	for i := range vars {
+		vars[i] = i + 1
+	}
+	// Drops nodes of failed branches and makes the root the last node.
+	c.d.reorder(c.compile(clauses, vars))
+	return c.d
+}
+
+func (c *dnnfCompiler) add(n DNNFNode) int {
+	c.d.Nodes = append(c.d.Nodes, n)
+	return len(c.d.Nodes) - 1
+}
+
+func (c *dnnfCompiler) lit(l Lit) int {
+	if id, ok := c.lits[l]; ok {
+		return id
+	}
+	id := c.add(DNNFNode{Kind: DNNFLit, Lit: l})
+	c.lits[l] = id
+	return id
+}
+
+// Node for a variable which is not constrained: v v -v
+func (c *dnnfCompiler) freeVar(v int) int {
+	if id, ok := c.free[v]; ok {
+		return id
+	}
+	id := c.add(DNNFNode{Kind: DNNFOr, Decision: v, Children: []int{c.lit(Lit(v)), c.lit(Lit(-v))}})
+	c.free[v] = id
+	return id
+}
+
+func (c *dnnfCompiler) and(children []int) int {
+	for _, child := range children {
+		if c.d.Nodes[child].Kind == DNNFFalse {
+			return c.constant(DNNFFalse)
+		}
+	}
+	if len(children) == 1 {
+		return children[0]
+	}
+	return c.add(DNNFNode{Kind: DNNFAnd, Children: children})
+}
+
+func (c *dnnfCompiler) constant(kind DNNFKind) int {
+	key := "false"
+	if kind == DNNFTrue {
+		key = "true"
+	}
+	if id, ok := c.cache[key]; ok {
+		return id
+	}
+	id := c.add(DNNFNode{Kind: kind})
+	c.cache[key] = id
+	return id
+}
+
+// Compiles clauses over exactly the given (sorted) variables.
+func (c *dnnfCompiler) compile(clauses []Clause, vars []int) int {
+	clauses, units, ok := propagateUnits(clauses)
+	if !ok {
+		return c.constant(DNNFFalse)
+	}
+	children := make([]int, 0)
+	assigned := make(map[int]bool)
+	for _, l := range units {
+		children = append(children, c.lit(l))
+		assigned[l.Var()] = true
+	}
+
+	components, covered := splitComponents(clauses)
+	for _, v := range vars {
+		if !assigned[v] && !covered[v] {
+			children = append(children, c.freeVar(v))
+		}
+	}
+	for _, comp := range components {
+		children = append(children, c.component(comp))
+	}
+	if len(children) == 0 {
+		return c.constant(DNNFTrue)
+	}
+	return c.and(children)
+}
+
+// Compiles a connected component by branching on its most frequent
+// variable.
+func (c *dnnfCompiler) component(comp []Clause) int {
+	key := componentKey(comp)
+	if id, ok := c.cache[key]; ok {
+		return id
+	}
+	count := make(map[int]int)
+	for _, cl := range comp {
+		for _, l := range cl {
+			count[l.Var()]++
+		}
+	}
+	vars := make([]int, 0, len(count))
+	for v := range count {
+		vars = append(vars, v)
+	}
+	sort.Ints(vars)
+	best := vars[0]
+	for _, v := range vars {
+		if count[v] > count[best] {
+			best = v
+		}
+	}
+	rest := make([]int, 0, len(vars)-1)
+	for _, v := range vars {
+		if v != best {
+			rest = append(rest, v)
+		}
+	}
+
+	branches := make([]int, 0, 2)
+	for _, l := range []Lit{Lit(best), Lit(-best)} {
+		sub := c.compile(condition(comp, l), rest)
+		if c.d.Nodes[sub].Kind == DNNFFalse {
+			continue
+		}
+		branches = append(branches, c.and([]int{c.lit(l), sub}))
+	}
+	var id int
+	switch len(branches) {
+	case 0:
+		id = c.constant(DNNFFalse)
+	case 1:
+		id = branches[0]
+	default:
+		id = c.add(DNNFNode{Kind: DNNFOr, Decision: best, Children: branches})
+	}
+	c.cache[key] = id
+	return id
+}
+
+// Sets l to true in clauses.
+func condition(clauses []Clause, l Lit) []Clause {
+	r := make([]Clause, 0, len(clauses))
+	for _, c := range clauses {
+		satisfied := false
+		reduced := make(Clause, 0, len(c))
+		for _, x := range c {
+			if x == l {
+				satisfied = true
+				break
+			}
+			if x != -l {
+				reduced = append(reduced, x)
+			}
+		}
+		if !satisfied {
+			r = append(r, reduced)
+		}
+	}
+	return r
+}
+
+// Applies unit clauses until none are left. Returns the remaining
+// clauses, the implied literals and false on conflict.
+func propagateUnits(clauses []Clause) ([]Clause, []Lit, bool) {
+	units := make([]Lit, 0)
+	for {
+		unit := Lit(0)
+		for _, c := range clauses {
+			if len(c) == 0 {
+				return nil, nil, false
+			}
+			if len(c) == 1 {
+				unit = c[0]
+				break
+			}
+		}
+		if unit == 0 {
+			return clauses, units, true
+		}
+		units = append(units, unit)
+		clauses = condition(clauses, unit)
+	}
+}
+
+// Splits clauses into groups connected by shared variables. Also
+// returns the set of variables occurring in clauses.
+func splitComponents(clauses []Clause) ([][]Clause, map[int]bool) {
+	parent := make(map[int]int)
+	var find func(int) int
+	find = func(v int) int {
+		for parent[v] != v {
+			parent[v] = parent[parent[v]]
+			v = parent[v]
+		}
+		return v
+	}
+	covered := make(map[int]bool)
+	for _, c := range clauses {
+		for _, l := range c {
+			if !covered[l.Var()] {
+				covered[l.Var()] = true
+				parent[l.Var()] = l.Var()
+			}
+		}
+		for _, l := range c[1:] {
+			parent[find(l.Var())] = find(c[0].Var())
+		}
+	}
+	groups := make(map[int][]Clause)
+	order := make([]int, 0)
+	for _, c := range clauses {
+		root := find(c[0].Var())
+		if _, ok := groups[root]; !ok {
+			order = append(order, root)
+		}
+		groups[root] = append(groups[root], c)
+	}
+	r := make([][]Clause, 0, len(groups))
+	for _, root := range order {
+		r = append(r, groups[root])
+	}
+	return r, covered
+}
+
+func componentKey(clauses []Clause) string {
+	keys := make([]string, len(clauses))
+	for i, c := range clauses {
+		keys[i] = clauseKey(c)
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, "|")
+}
+
+// Count returns the number of models over all NumVars variables.
+func (d *DNNF) Count() *big.Int {
+	counts := make([]*big.Int, len(d.Nodes))
+	for i, n := range d.Nodes {
+		switch n.Kind {
+		case DNNFTrue, DNNFLit:
+			counts[i] = big.NewInt(1)
+		case DNNFFalse:
+			counts[i] = big.NewInt(0)
+		case DNNFAnd:
+			counts[i] = big.NewInt(1)
+			for _, child := range n.Children {
+				counts[i].Mul(counts[i], counts[child])
+			}
+		case DNNFOr:
+			counts[i] = big.NewInt(0)
+			for _, child := range n.Children {
+				counts[i].Add(counts[i], counts[child])
+			}
+		}
+	}
+	return counts[d.Root]
+}
+
+// Probability returns the probability that the formula holds if
+// every variable v is true independently with probability p[v].
+func (d *DNNF) Probability(p []float64) float64 {
+	probs := make([]float64, len(d.Nodes))
+	for i, n := range d.Nodes {
+		switch n.Kind {
+		case DNNFTrue:
+			probs[i] = 1
+		case DNNFFalse:
+			probs[i] = 0
+		case DNNFLit:
+			probs[i] = p[n.Lit.Var()]
+			if n.Lit < 0 {
+				probs[i] = 1 - probs[i]
+			}
+		case DNNFAnd:
+			probs[i] = 1
+			for _, child := range n.Children {
+				probs[i] *= probs[child]
+			}
+		case DNNFOr:
+			probs[i] = 0
+			for _, child := range n.Children {
+				probs[i] += probs[child]
+			}
+		}
+	}
+	return probs[d.Root]
+}
+
+// Condition returns the DNNF of the formula with the given literals
+// set to true. Counts of the result refer to the remaining variables.
+func (d *DNNF) Condition(lits ...Lit) *DNNF {
+	value := make(map[Lit]bool)
+	for _, l := range lits {
+		value[l] = true
+	}
+	r := &DNNF{
+		NumVars: d.NumVars,
+		Nodes:   make([]DNNFNode, len(d.Nodes)),
+		Root:    d.Root,
+	}
+	for i, n := range d.Nodes {
+		r.Nodes[i] = n
+		if n.Kind != DNNFLit {
+			continue
+		}
+		if value[n.Lit] {
+			r.Nodes[i] = DNNFNode{Kind: DNNFTrue}
+		} else if value[-n.Lit] {
+			r.Nodes[i] = DNNFNode{Kind: DNNFFalse}
+		}
+	}
+	return r
+}
+
+// MinCard returns a model with the smallest number of true variables,
+// or false if there is none.
+func (d *DNNF) MinCard() ([]bool, bool) {
+	const inf = int(^uint(0) >> 1)
+	card := make([]int, len(d.Nodes))
+	for i, n := range d.Nodes {
+		switch n.Kind {
+		case DNNFTrue:
+			card[i] = 0
+		case DNNFFalse:
+			card[i] = inf
+		case DNNFLit:
+			card[i] = 0
+			if n.Lit > 0 {
+				card[i] = 1
+			}
+		case DNNFAnd:
+			for _, child := range n.Children {
+				if card[child] == inf {
+					card[i] = inf
+					break
+				}
+				card[i] += card[child]
+			}
+		case DNNFOr:
+			card[i] = inf
+			for _, child := range n.Children {
+				if card[child] < card[i] {
+					card[i] = card[child]
+				}
+			}
+		}
+	}
+	if card[d.Root] == inf {
+		return nil, false
+	}
+	model := make([]bool, d.NumVars+1)
+	stack := []int{d.Root}
+	for len(stack) > 0 {
+		n := d.Nodes[stack[len(stack)-1]]
+		stack = stack[:len(stack)-1]
+		switch n.Kind {
+		case DNNFLit:
+			model[n.Lit.Var()] = n.Lit > 0
+		case DNNFAnd:
+			stack = append(stack, n.Children...)
+		case DNNFOr:
+			best := n.Children[0]
+			for _, child := range n.Children {
+				if card[child] < card[best] {
+					best = child
+				}
+			}
+			stack = append(stack, best)
+		}
+	}
+	return model, true
+}
+
+// Enumerate calls f with every model until f returns false. The
+// model slice is reused between calls.
+func (d *DNNF) Enumerate(f func([]bool) bool) {
+	model := make([]bool, d.NumVars+1)
+	d.enumerate([]int{d.Root}, model, f)
+}
+
+func (d *DNNF) enumerate(stack []int, model []bool, f func([]bool) bool) bool {
+	if len(stack) == 0 {
+		return f(model)
+	}
+	last := len(stack) - 1
+	n := d.Nodes[stack[last]]
+	// Full slice so that appends do not overwrite the callers stack.
+	rest := stack[:last:last]
+	switch n.Kind {
+	case DNNFFalse:
+		return true
+	case DNNFLit:
+		model[n.Lit.Var()] = n.Lit > 0
+	case DNNFAnd:
+		return d.enumerate(append(rest, n.Children...), model, f)
+	case DNNFOr:
+		for _, child := range n.Children {
+			if !d.enumerate(append(rest, child), model, f) {
+				return false
+			}
+		}
+		return true
+	}
+	return d.enumerate(rest, model, f)
+}
+
+// WriteNNF writes d in the NNF format of c2d and d4:
+//
+//	nnf <nodes> <edges> <variables>
+//	L <literal>
+//	A <k> <children>
+//	O <decision variable> <k> <children>
+func (d *DNNF) WriteNNF(w io.Writer) error {
+	b := bufio.NewWriter(w)
+	edges := 0
+	for _, n := range d.Nodes {
+		edges += len(n.Children)
+	}
+	fmt.Fprintf(b, "nnf %d %d %d\n", len(d.Nodes), edges, d.NumVars)
+	for _, n := range d.Nodes {
+		switch n.Kind {
+		case DNNFTrue:
+			fmt.Fprintf(b, "A 0\n")
+		case DNNFFalse:
+			fmt.Fprintf(b, "O 0 0\n")
+		case DNNFLit:
+			fmt.Fprintf(b, "L %d\n", n.Lit)
+		case DNNFAnd:
+			fmt.Fprintf(b, "A %d%s\n", len(n.Children), formatInts(n.Children))
+		case DNNFOr:
+			fmt.Fprintf(b, "O %d %d%s\n", n.Decision, len(n.Children), formatInts(n.Children))
+		}
+	}
+	return b.Flush()
+}
+
+func formatInts(a []int) string {
+	s := ""
+	for _, x := range a {
+		s += " " + strconv.Itoa(x)
+	}
+	return s
+}
+
+// ReadNNF reads a DNNF in the format written by WriteNNF. The last
+// node is the root. Since other compilers do not necessarily produce
+// smooth circuits, the result is smoothed.
+func ReadNNF(r io.Reader) (*DNNF, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1<<30)
+	d := &DNNF{}
+	header := false
+	for lineno := 1; sc.Scan(); lineno++ {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 0 || fields[0] == "c" {
+			continue
+		}
+		nums := make([]int, len(fields)-1)
+		for i, field := range fields[1:] {
+			v, e := strconv.Atoi(field)
+			if e != nil {
+				return nil, fmt.Errorf("Line %d: invalid number %s", lineno, field)
+			}
+			nums[i] = v
+		}
+		if !header {
+			if fields[0] != "nnf" || len(nums) != 3 {
+				return nil, fmt.Errorf("Line %d: invalid header", lineno)
+			}
+			d.NumVars = nums[2]
+			header = true
+			continue
+		}
+		n := DNNFNode{}
+		switch {
+		case fields[0] == "L" && len(nums) == 1:
+			n.Kind, n.Lit = DNNFLit, Lit(nums[0])
+			if n.Lit.Var() > d.NumVars || n.Lit == 0 {
+				return nil, fmt.Errorf("Line %d: invalid literal", lineno)
+			}
+		case fields[0] == "A" && len(nums) >= 1 && len(nums) == nums[0]+1:
+			n.Kind, n.Children = DNNFAnd, nums[1:]
+			if nums[0] == 0 {
+				n.Kind = DNNFTrue
+			}
+		case fields[0] == "O" && len(nums) >= 2 && len(nums) == nums[1]+2:
+			n.Kind, n.Decision, n.Children = DNNFOr, nums[0], nums[2:]
+			if nums[1] == 0 {
+				n.Kind = DNNFFalse
+			}
+		default:
+			return nil, fmt.Errorf("Line %d: invalid node", lineno)
+		}
+		for _, child := range n.Children {
+			if child < 0 || child >= len(d.Nodes) {
+				return nil, fmt.Errorf("Line %d: invalid child %d", lineno, child)
+			}
+		}
+		d.Nodes = append(d.Nodes, n)
+	}
+	if e := sc.Err(); e != nil {
+		return nil, e
+	}
+	if len(d.Nodes) == 0 {
+		return nil, fmt.Errorf("No nodes")
+	}
+	d.Root = len(d.Nodes) - 1
+	d.smooth()
+	return d, nil
+}
+
+// Makes every disjunction mention the same variables in all its
+// children and the root mention every variable by adding v v -v
+// nodes.
+func (d *DNNF) smooth() {
+	vars := make([][]int, len(d.Nodes))
+	free := make(map[int]int)
+	freeVar := func(v int) int {
+		if id, ok := free[v]; ok {
+			return id
+		}
+		pos := len(d.Nodes)
+		d.Nodes = append(d.Nodes, DNNFNode{Kind: DNNFLit, Lit: Lit(v)}, DNNFNode{Kind: DNNFLit, Lit: Lit(-v)},
+			DNNFNode{Kind: DNNFOr, Decision: v, Children: []int{pos, pos + 1}})
+		free[v] = pos + 2
+		return pos + 2
+	}
+	// Adds free variables so that node i mentions all of want.
+	// New nodes are appended, so indices stay valid.
+	pad := func(i int, have, want []int) int {
+		missing := difference(want, have)
+		if len(missing) == 0 {
+			return i
+		}
+		children := []int{i}
+		for _, v := range missing {
+			children = append(children, freeVar(v))
+		}
+		d.Nodes = append(d.Nodes, DNNFNode{Kind: DNNFAnd, Children: children})
+		return len(d.Nodes) - 1
+	}
+
+	n := len(d.Nodes)
+	for i := 0; i < n; i++ {
+		node := d.Nodes[i]
+		switch node.Kind {
+		case DNNFLit:
+			vars[i] = []int{node.Lit.Var()}
+		case DNNFAnd:
+			for _, child := range node.Children {
+				vars[i] = union(vars[i], vars[child])
+			}
+		case DNNFOr:
+			for _, child := range node.Children {
+				vars[i] = union(vars[i], vars[child])
+			}
+			children := make([]int, len(node.Children))
+			for j, child := range node.Children {
+				children[j] = pad(child, vars[child], vars[i])
+			}
+			d.Nodes[i].Children = children
+		}
+	}
+	// Padded children were appended behind their parents. Restore
+	// the topological order by moving every original node behind
+	// the new ones.
+	all := make([]int, d.NumVars)
+	for i := range all {
+		all[i] = i + 1
+	}
+	root := pad(d.Root, vars[d.Root], all)
+	d.reorder(root)
+}
+
+// Renumbers the nodes reachable from root so that children come
+// before their parents and root is the last node.
+func (d *DNNF) reorder(root int) {
+	ids := make(map[int]int)
+	nodes := make([]DNNFNode, 0, len(d.Nodes))
+	var visit func(int) int
+	visit = func(i int) int {
+		if id, ok := ids[i]; ok {
+			return id
+		}
+		n := d.Nodes[i]
+		children := make([]int, len(n.Children))
+		for j, child := range n.Children {
+			children[j] = visit(child)
+		}
+		n.Children = children
+		nodes = append(nodes, n)
+		ids[i] = len(nodes) - 1
+		return len(nodes) - 1
+	}
+	d.Root = visit(root)
+	d.Nodes = nodes
+}
+
+// Both slices sorted.
+func union(a, b []int) []int {
+	r := make([]int, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case j == len(b) || (i < len(a) && a[i] < b[j]):
+			r = append(r, a[i])
+			i++
+		case i == len(a) || b[j] < a[i]:
+			r = append(r, b[j])
+			j++
+		default:
+			r = append(r, a[i])
+			i++
+			j++
+		}
+	}
+	return r
+}
+
+// Elements of a not in b, both sorted.
+func difference(a, b []int) []int {
+	r := make([]int, 0)
+	j := 0
+	for _, x := range a {
+		for j < len(b) && b[j] < x {
+			j++
+		}
+		if j == len(b) || b[j] != x {
+			r = append(r, x)
+		}
+	}
+	return r
+}
@@ -0,0 +1,112 @@
+package logic
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func countModels(cs *ClauseSet, f func([]bool) bool) int64 {
+	n := int64(0)
+	allModels(cs, func(model []bool) {
+		if f(model) {
+			n++
+		}
+	})
+	return n
+}
+
+func TestCompileDNNF(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		cs := randomClauseSet(rng, 8, rng.Intn(30), 3)
+		d := CompileDNNF(cs)
+		all := func([]bool) bool { return true }
+		if n := d.Count().Int64(); n != countModels(cs, all) {
+			t.Fatalf("Count of %s is %d, expected %d", cs.DIMACS(), n, countModels(cs, all))
+		}
+
+		// Knock out variable 1 and force variable 2.
+		cond := d.Condition(Lit(-1), Lit(2))
+		expected := countModels(cs, func(m []bool) bool { return !m[1] && m[2] })
+		if n := cond.Count().Int64(); n != expected {
+			t.Fatalf("Conditioned count of %s is %d, expected %d", cs.DIMACS(), n, expected)
+		}
+
+		min := -1
+		allModels(cs, func(m []bool) {
+			k := 0
+			for _, b := range m[1:] {
+				if b {
+					k++
+				}
+			}
+			if min < 0 || k < min {
+				min = k
+			}
+		})
+		model, ok := d.MinCard()
+		if ok != (min >= 0) {
+			t.Fatalf("MinCard of %s returned %v", cs.DIMACS(), ok)
+		}
+		if ok {
+			k := 0
+			for _, b := range model[1:] {
+				if b {
+					k++
+				}
+			}
+			if k != min || !cs.Satisfies(model) {
+				t.Fatalf("MinCard of %s returned %v, expected cardinality %d", cs.DIMACS(), model, min)
+			}
+		}
+
+		seen := make(map[string]bool)
+		d.Enumerate(func(m []bool) bool {
+			if !cs.Satisfies(m) {
+				t.Fatalf("Enumerate of %s returned non-model %v", cs.DIMACS(), m)
+			}
+			seen[fmt.Sprint(m)] = true
+			return true
+		})
+		if int64(len(seen)) != countModels(cs, all) {
+			t.Fatalf("Enumerate of %s returned %d distinct models", cs.DIMACS(), len(seen))
+		}
+	}
+}
+
+func TestNNFRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	for i := 0; i < 50; i++ {
+		cs := randomClauseSet(rng, 10, rng.Intn(40), 3)
+		d := CompileDNNF(cs)
+		var b bytes.Buffer
+		if e := d.WriteNNF(&b); e != nil {
+			t.Fatal(e)
+		}
+		r, e := ReadNNF(&b)
+		if e != nil {
+			t.Fatal(e)
+		}
+		if r.Count().Cmp(d.Count()) != 0 {
+			t.Fatalf("Count changed from %s to %s", d.Count(), r.Count())
+		}
+	}
+}
+
+func TestReadNNFSmooths(t *testing.T) {
+	// (1 and 2) or -1 over three variables: 2 + 4 models.
+	nnf := "nnf 6 5 3\nL 1\nL 2\nL -1\nA 2 0 1\nO 1 2 3 2\n"
+	d, e := ReadNNF(strings.NewReader(nnf))
+	if e != nil {
+		t.Fatal(e)
+	}
+	if n := d.Count().Int64(); n != 6 {
+		t.Fatalf("Count is %d, expected 6", n)
+	}
+	if p := d.Probability([]float64{0, 0.5, 0.5, 0.5}); p != 0.75 {
+		t.Fatalf("Probability is %v, expected 0.75", p)
+	}
+}
@@ -0,0 +1,480 @@
+package logic
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ParseDIMACS reads a CNF in DIMACS format as written by FormatSAT.
+// Variables are named by their index.
+func ParseDIMACS(r io.Reader) (*ClauseSet, error) {
+	cs := NewClauseSet()
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1<<30)
+	c := make(Clause, 0)
+	header := false
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || line[0] == 'c' {
+			continue
+		}
+		// SATLIB files end in "%" and "0"
+		if line[0] == '%' {
+			break
+		}
+		if line[0] == 'p' {
+			fields := strings.Fields(line)
+			if len(fields) != 4 || fields[1] != "cnf" {
+				return nil, fmt.Errorf("Invalid header: %s", line)
+			}
+			n, e := strconv.Atoi(fields[2])
+			if e != nil {
+				return nil, fmt.Errorf("Invalid header: %s", line)
+			}
+			for v := 1; v <= n; v++ {
+				cs.Var(strconv.Itoa(v))
+			}
+			header = true
+			continue
+		}
+		if !header {
+			return nil, fmt.Errorf("Clause before header: %s", line)
+		}
+		for _, field := range strings.Fields(line) {
+			v, e := strconv.Atoi(field)
+			if e != nil {
+				return nil, fmt.Errorf("Invalid literal %s", field)
+			}
+			if v == 0 {
+				cs.Clauses = append(cs.Clauses, c)
+				c = make(Clause, 0)
+				continue
+			}
+			l := Lit(v)
+			cs.grow(l.Var())
+			c = append(c, l)
+		}
+	}
+	if e := sc.Err(); e != nil {
+		return nil, e
+	}
+	if len(c) != 0 {
+		return nil, fmt.Errorf("Last clause is not terminated")
+	}
+	for v := 1; v <= cs.NumVars; v++ {
+		if cs.Names[v] == "" {
+			name := strconv.Itoa(v)
+			cs.Names[v] = name
+			cs.Index[name] = v
+		}
+	}
+	return cs, nil
+}
+
+type proofStep struct {
+	delete bool
+	lits   []Lit
+}
+
+// Reads a DRAT proof in text or binary format. Binary proofs are
+// recognized by bytes which cannot appear in text proofs outside of
+// comment lines.
+func readDRAT(r io.Reader) ([]proofStep, error) {
+	data, e := ioutil.ReadAll(r)
+	if e != nil {
+		return nil, e
+	}
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		if line = bytes.TrimLeft(line, " \t\r"); len(line) > 0 && line[0] == 'c' {
+			continue
+		}
+		for _, b := range line {
+			if !strings.ContainsRune("0123456789-d \t\r", rune(b)) {
+				return readBinaryDRAT(data)
+			}
+		}
+	}
+	return readTextDRAT(data)
+}
+
+func readTextDRAT(data []byte) ([]proofStep, error) {
+	steps := make([]proofStep, 0)
+	cur := proofStep{}
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		fields := strings.Fields(string(line))
+		if len(fields) == 0 || fields[0] == "c" {
+			continue
+		}
+		if fields[0] == "d" {
+			cur.delete = true
+			fields = fields[1:]
+		}
+		for _, field := range fields {
+			v, e := strconv.Atoi(field)
+			if e != nil {
+				return nil, fmt.Errorf("Invalid literal %s in proof", field)
+			}
+			if v == 0 {
+				steps = append(steps, cur)
+				cur = proofStep{}
+				continue
+			}
+			cur.lits = append(cur.lits, Lit(v))
+		}
+	}
+	if cur.delete || len(cur.lits) > 0 {
+		return nil, fmt.Errorf("Last proof step is not terminated")
+	}
+	return steps, nil
+}
+
+func readBinaryDRAT(data []byte) ([]proofStep, error) {
+	steps := make([]proofStep, 0)
+	for i := 0; i < len(data); {
+		cur := proofStep{}
+		switch data[i] {
+		case 'a':
+		case 'd':
+			cur.delete = true
+		default:
+			return nil, fmt.Errorf("Invalid binary proof step 0x%02x at byte %d", data[i], i)
+		}
+		i++
+		for {
+			var u uint64
+			shift := uint(0)
+			for {
+				if i >= len(data) {
+					return nil, fmt.Errorf("Truncated binary proof")
+				}
+				b := data[i]
+				i++
+				u |= uint64(b&0x7f) << shift
+				shift += 7
+				if b&0x80 == 0 {
+					break
+				}
+			}
+			if u == 0 {
+				break
+			}
+			l := Lit(u >> 1)
+			if u&1 == 1 {
+				l = -l
+			}
+			cur.lits = append(cur.lits, l)
+		}
+		steps = append(steps, cur)
+	}
+	return steps, nil
+}
+
+// The checker reuses the solver's watched literal propagation.
+// Top-level assignments live on level 0, lemma checks happen on
+// level 1.
+type checker struct {
+	s *Solver
+	// Sorted literal lists to the live clauses with these literals.
+	byKey    map[string][]*clause
+	refuted  bool
+	stepDesc string
+}
+
+func newChecker(cs *ClauseSet) *checker {
+	c := &checker{
+		s:     NewSolver(cs.NumVars),
+		byKey: make(map[string][]*clause),
+	}
+	for _, cl := range cs.Clauses {
+		c.add(cl)
+	}
+	return c
+}
+
+func clauseKey(lits []Lit) string {
+	sorted := append([]Lit(nil), lits...)
+	sort.Sort(litSlice(sorted))
+	var b bytes.Buffer
+	for i, l := range sorted {
+		if i > 0 && sorted[i-1] == l {
+			continue
+		}
+		b.WriteString(strconv.Itoa(int(l)))
+		b.WriteByte(' ')
+	}
+	return b.String()
+}
+
+// Adds a clause to the database and propagates it if it is unit.
+func (c *checker) add(lits []Lit) {
+	if c.refuted {
+		return
+	}
+	s := c.s
+	for _, l := range lits {
+		s.ensureVars(l.Var())
+	}
+	uniq := make([]Lit, 0, len(lits))
+	seen := make(map[Lit]bool)
+	for _, l := range lits {
+		if !seen[l] {
+			seen[l] = true
+			uniq = append(uniq, l)
+		}
+	}
+	// Move unassigned literals to the front, so the watches are
+	// valid on level 0.
+	sort.SliceStable(uniq, func(i, j int) bool {
+		return s.value(uniq[i]) != lFalse && s.value(uniq[j]) == lFalse
+	})
+	cl := &clause{lits: uniq}
+	key := clauseKey(uniq)
+	c.byKey[key] = append(c.byKey[key], cl)
+	if len(uniq) == 0 {
+		c.refuted = true
+		return
+	}
+	if len(uniq) > 1 {
+		s.clauses = append(s.clauses, cl)
+		s.attach(cl)
+	}
+	if len(uniq) == 1 || s.value(uniq[1]) == lFalse {
+		switch s.value(uniq[0]) {
+		case lFalse:
+			c.refuted = true
+		case lUndef:
+			s.enqueue(uniq[0], cl)
+			if s.propagate() != nil {
+				c.refuted = true
+			}
+		}
+	}
+}
+
+// Deletions of clauses which are reasons for top-level assignments
+// are ignored, like drat-trim does by default.
+func (c *checker) delete(lits []Lit) error {
+	key := clauseKey(lits)
+	list := c.byKey[key]
+	if len(list) == 0 {
+		return fmt.Errorf("%s: deleted clause %v is not in the database", c.stepDesc, lits)
+	}
+	cl := list[len(list)-1]
+	if len(cl.lits) > 0 && c.s.reason[cl.lits[0].Var()] == cl && c.s.value(cl.lits[0]) == lTrue {
+		return nil
+	}
+	c.byKey[key] = list[:len(list)-1]
+	cl.deleted = true
+	return nil
+}
+
+// Reports whether assigning the negation of lits leads to a
+// conflict by unit propagation.
+func (c *checker) rup(lits []Lit) bool {
+	if c.refuted {
+		return true
+	}
+	s := c.s
+	for _, l := range lits {
+		s.ensureVars(l.Var())
+	}
+	s.trailLim = append(s.trailLim, len(s.trail))
+	defer s.cancelUntil(0)
+	for _, l := range lits {
+		switch s.value(l) {
+		case lTrue:
+			return true
+		case lUndef:
+			s.enqueue(-l, nil)
+		}
+	}
+	return s.propagate() != nil
+}
+
+// Checks the resolution asymmetric tautology property on the first
+// literal of lits.
+func (c *checker) rat(lits []Lit) bool {
+	if len(lits) == 0 {
+		return false
+	}
+	pivot := lits[0]
+	// Unit clauses are only kept in byKey, not among the solver's
+	// clauses, but take part in the resolution as well.
+	for _, list := range c.byKey {
+		for _, cl := range list {
+			if !c.resolvesRUP(lits, pivot, cl) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Reports whether the resolvent of lits and cl on pivot is RUP, or
+// cl does not contain the negated pivot.
+func (c *checker) resolvesRUP(lits []Lit, pivot Lit, cl *clause) bool {
+	contains := false
+	for _, l := range cl.lits {
+		if l == -pivot {
+			contains = true
+			break
+		}
+	}
+	if !contains {
+		return true
+	}
+	resolvent := append([]Lit(nil), lits...)
+	for _, l := range cl.lits {
+		if l != -pivot {
+			resolvent = append(resolvent, l)
+		}
+	}
+	return c.rup(resolvent)
+}
+
+// CheckDRAT verifies that proof, a DRAT proof in text or binary
+// format, refutes cs. Every lemma is checked forward for RUP, and
+// for RAT on its first literal if that fails. The proof is accepted
+// once the empty clause has been derived.
+func CheckDRAT(cs *ClauseSet, proof io.Reader) error {
+	steps, e := readDRAT(proof)
+	if e != nil {
+		return e
+	}
+	c := newChecker(cs)
+	for i, step := range steps {
+		if c.refuted {
+			return nil
+		}
+		c.stepDesc = fmt.Sprintf("Proof step %d", i+1)
+		if step.delete {
+			if e := c.delete(step.lits); e != nil {
+				return e
+			}
+			continue
+		}
+		if !c.rup(step.lits) && !c.rat(step.lits) {
+			return fmt.Errorf("%s: lemma %v is neither RUP nor RAT", c.stepDesc, step.lits)
+		}
+		c.add(step.lits)
+	}
+	if !c.refuted {
+		return fmt.Errorf("Proof does not derive the empty clause")
+	}
+	return nil
+}
+
+// CheckLRAT verifies a text LRAT proof against cs. Clause ids 1 to
+// len(cs.Clauses) denote the original clauses in order. Only RUP
+// hints are supported, RAT hints (negative ids) are rejected.
+func CheckLRAT(cs *ClauseSet, proof io.Reader) error {
+	db := make(map[int][]Lit)
+	for i, cl := range cs.Clauses {
+		db[i+1] = cl
+	}
+	sc := bufio.NewScanner(proof)
+	sc.Buffer(make([]byte, 64*1024), 1<<30)
+	for lineno := 1; sc.Scan(); lineno++ {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 0 || fields[0] == "c" {
+			continue
+		}
+		nums := make([]int, 0, len(fields))
+		isDelete := false
+		for i, field := range fields {
+			if i == 1 && field == "d" {
+				isDelete = true
+				continue
+			}
+			v, e := strconv.Atoi(field)
+			if e != nil {
+				return fmt.Errorf("Line %d: invalid number %s", lineno, field)
+			}
+			nums = append(nums, v)
+		}
+		if len(nums) < 2 || nums[len(nums)-1] != 0 {
+			return fmt.Errorf("Line %d: step is not terminated", lineno)
+		}
+		id := nums[0]
+		nums = nums[1 : len(nums)-1]
+		if isDelete {
+			for _, del := range nums {
+				delete(db, del)
+			}
+			continue
+		}
+		if _, ok := db[id]; ok {
+			return fmt.Errorf("Line %d: clause id %d is already in use", lineno, id)
+		}
+		sep := -1
+		for i, v := range nums {
+			if v == 0 {
+				sep = i
+				break
+			}
+		}
+		if sep < 0 {
+			return fmt.Errorf("Line %d: missing hints", lineno)
+		}
+		lits := make([]Lit, sep)
+		for i := range lits {
+			lits[i] = Lit(nums[i])
+		}
+		if e := checkHints(db, lits, nums[sep+1:]); e != nil {
+			return fmt.Errorf("Line %d: %s", lineno, e)
+		}
+		if len(lits) == 0 {
+			return nil
+		}
+		db[id] = lits
+	}
+	if e := sc.Err(); e != nil {
+		return e
+	}
+	return fmt.Errorf("Proof does not derive the empty clause")
+}
+
+// Replays the unit propagation chain given by hints under the
+// negation of lits. The chain has to end in a falsified clause.
+func checkHints(db map[int][]Lit, lits []Lit, hints []int) error {
+	assignment := make(map[Lit]bool)
+	for _, l := range lits {
+		assignment[-l] = true
+	}
+	for _, hint := range hints {
+		if hint < 0 {
+			return fmt.Errorf("RAT hints are not supported")
+		}
+		cl, ok := db[hint]
+		if !ok {
+			return fmt.Errorf("Hint %d refers to an unknown clause", hint)
+		}
+		unit := Lit(0)
+		open := 0
+		for _, l := range cl {
+			if assignment[l] {
+				open = -1
+				break
+			}
+			if !assignment[-l] && l != unit {
+				unit = l
+				open++
+			}
+		}
+		switch open {
+		case 0:
+			return nil
+		case 1:
+			assignment[unit] = true
+		default:
+			return fmt.Errorf("Hint %d is neither unit nor falsified", hint)
+		}
+	}
+	return fmt.Errorf("Hints do not lead to a conflict")
+}
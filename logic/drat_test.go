@@ -0,0 +1,125 @@
+package logic
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func pigeonhole(pigeons, holes int) *ClauseSet {
+	cs := NewClauseSet()
+	p := func(i, j int) Lit {
+		return Lit(i*holes + j + 1)
+	}
+	for i := 0; i < pigeons; i++ {
+		c := make(Clause, holes)
+		for j := range c {
+			c[j] = p(i, j)
+		}
+		cs.AddClause(c...)
+	}
+	for j := 0; j < holes; j++ {
+		for i := 0; i < pigeons; i++ {
+			for k := i + 1; k < pigeons; k++ {
+				cs.AddClause(-p(i, j), -p(k, j))
+			}
+		}
+	}
+	return cs
+}
+
+func TestSolverProof(t *testing.T) {
+	cs := pigeonhole(6, 5)
+	var proof bytes.Buffer
+	s := NewSolver(cs.NumVars)
+	s.Proof = &proof
+	for _, c := range cs.Clauses {
+		s.AddClause(c...)
+	}
+	if s.Solve() {
+		t.Fatalf("Pigeonhole formula is satisfiable")
+	}
+	parsed, e := ParseDIMACS(strings.NewReader(cs.DIMACS()))
+	if e != nil {
+		t.Fatalf("Could not parse DIMACS: %s", e)
+	}
+	if e := CheckDRAT(parsed, bytes.NewReader(proof.Bytes())); e != nil {
+		t.Fatalf("Proof rejected: %s", e)
+	}
+
+	// Dropping the empty clause and the lemmas before it must break
+	// the proof.
+	lines := strings.Split(strings.TrimSpace(proof.String()), "\n")
+	truncated := strings.Join(lines[:len(lines)/2], "\n") + "\n"
+	if e := CheckDRAT(parsed, strings.NewReader(truncated)); e == nil {
+		t.Fatalf("Truncated proof accepted")
+	}
+}
+
+func TestCheckDRAT(t *testing.T) {
+	cnf := "p cnf 2 4\n1 2 0\n-1 2 0\n1 -2 0\n-1 -2 0\n"
+	cs, e := ParseDIMACS(strings.NewReader(cnf))
+	if e != nil {
+		t.Fatalf("Could not parse DIMACS: %s", e)
+	}
+	valid := []string{
+		"1 0\n0\n",
+		// Vacuous RAT lemma on a fresh variable
+		"3 0\n-1 0\nd -1 -2 0\n0\n",
+		// Same as the first one, binary
+		"a\x02\x00a\x00",
+		"c written by a solver\n1 0\nc done\n0\n",
+	}
+	for _, proof := range valid {
+		if e := CheckDRAT(cs, strings.NewReader(proof)); e != nil {
+			t.Fatalf("Proof %q rejected: %s", proof, e)
+		}
+	}
+	invalid := []string{
+		"",
+		"1 2 0\n",
+		"d 1 2 0\n-1 0\n0\n",
+	}
+	for _, proof := range invalid {
+		if e := CheckDRAT(cs, strings.NewReader(proof)); e == nil {
+			t.Fatalf("Proof %q accepted", proof)
+		}
+	}
+}
+
+// Lemmas clashing with unit clauses must not pass as RAT.
+func TestCheckDRATUnits(t *testing.T) {
+	for _, cnf := range []string{
+		"p cnf 1 1\n-1 0\n",
+		"p cnf 2 2\n-1 0\n2 0\n",
+	} {
+		cs, e := ParseDIMACS(strings.NewReader(cnf))
+		if e != nil {
+			t.Fatalf("Could not parse DIMACS: %s", e)
+		}
+		if e := CheckDRAT(cs, strings.NewReader("1 0\n")); e == nil {
+			t.Fatalf("Refutation of satisfiable %q accepted", cnf)
+		}
+	}
+}
+
+// SATLIB files end in a line "%" followed by "0".
+func TestParseDIMACSSATLIB(t *testing.T) {
+	cs, e := ParseDIMACS(strings.NewReader("c uf2\np cnf 2 1\n1 -2 0\n%\n0\n\n"))
+	if e != nil {
+		t.Fatalf("Could not parse DIMACS: %s", e)
+	}
+	if len(cs.Clauses) != 1 {
+		t.Fatalf("Parsed %d clauses", len(cs.Clauses))
+	}
+}
+
+func TestCheckLRAT(t *testing.T) {
+	cs, _ := ParseDIMACS(strings.NewReader("p cnf 2 4\n1 2 0\n-1 2 0\n1 -2 0\n-1 -2 0\n"))
+	if e := CheckLRAT(cs, strings.NewReader("5 1 0 1 3 0\n5 d 1 3 0\n6 0 5 2 4 0\n")); e != nil {
+		t.Fatalf("Proof rejected: %s", e)
+	}
+	if e := CheckLRAT(cs, strings.NewReader("5 1 0 1 0\n6 0 5 2 4 0\n")); e == nil {
+		t.Fatalf("Proof with wrong hints accepted")
+	}
+}
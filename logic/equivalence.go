@@ -0,0 +1,116 @@
+package logic
+
+// Equivalent decides whether a and b have the same value under every
+// configuration by solving the miter a xor b. If they differ, a
+// configuration of the leafs of both on which they do is returned.
+func Equivalent(a, b Node) (bool, Configuration) {
+	cs := NewClauseSet()
+	la, lb := cs.Tseitin(a), cs.Tseitin(b)
+	cs.AddClause(la, lb)
+	cs.AddClause(-la, -lb)
+	s := NewSolverFromClauses(cs)
+	if !s.Solve() {
+		return true, nil
+	}
+	return false, cs.Configuration(s.Model())
+}
+
+// Equisatisfiable decides whether, for every assignment of the
+// shared leafs, a can be satisfied exactly if b can. All other leafs
+// are existentially quantified, separately for a and b, so the
+// Tseitin encoding of a formula is equisatisfiable with it over its
+// leafs. If not, the returned configuration assigns the shared leafs
+// such that one formula is satisfiable and the other is not, together
+// with the remaining leafs of a model of the satisfiable one.
+func Equisatisfiable(a, b Node, shared []string) (bool, Configuration) {
+	if ok, c := implies(a, b, shared); !ok {
+		return false, c
+	}
+	return implies(b, a, shared)
+}
+
+// Checks that every assignment of the shared leafs which extends to
+// a model of a extends to a model of b. Models of a are enumerated
+// with a solver; for each one, a second solver looks for a model of b
+// under the same shared assignment. If there is one, the shared
+// literals it relies on are blocked in the first solver.
+func implies(a, b Node, shared []string) (bool, Configuration) {
+	// Shared leafs get the same variables in both encodings, all
+	// other leafs and operations are kept apart by encoding a and b
+	// into separate clause sets numbered after the shared ones.
+	base := NewClauseSet()
+	for _, name := range shared {
+		base.Var(name)
+	}
+	isShared := make([]bool, base.NumVars+1)
+	for v := 1; v <= base.NumVars; v++ {
+		isShared[v] = true
+	}
+	ca, cb := base.copyVars(), base.copyVars()
+	ca.AddClause(ca.Tseitin(a))
+	cb.AddClause(cb.Tseitin(b))
+	sa, sb := NewSolverFromClauses(ca), NewSolverFromClauses(cb)
+
+	for sa.Solve() {
+		model := sa.Model()
+		assumptions := make([]Lit, 0, base.NumVars)
+		for v := 1; v <= base.NumVars; v++ {
+			l := Lit(v)
+			if !model[v] {
+				l = -l
+			}
+			assumptions = append(assumptions, l)
+		}
+		if !sb.Solve(assumptions...) {
+			return false, ca.Configuration(model)
+		}
+		blocking := make([]Lit, 0)
+		for _, l := range lift(cb, sb.Model(), isShared) {
+			blocking = append(blocking, -l)
+		}
+		if !sa.AddClause(blocking...) {
+			break
+		}
+	}
+	return true, nil
+}
+
+// Returns shared literals of model which, together with its
+// unshared part, satisfy every clause of cs, so that every extension
+// of them is a model.
+func lift(cs *ClauseSet, model []bool, isShared []bool) []Lit {
+	chosen := make(map[Lit]bool)
+	r := make([]Lit, 0)
+	for _, c := range cs.Clauses {
+		var candidate Lit
+		satisfied := false
+		for _, l := range c {
+			if model[l.Var()] != (l > 0) {
+				continue
+			}
+			if l.Var() >= len(isShared) || !isShared[l.Var()] || chosen[l] {
+				satisfied = true
+				break
+			}
+			candidate = l
+		}
+		if !satisfied {
+			chosen[candidate] = true
+			r = append(r, candidate)
+		}
+	}
+	return r
+}
+
+// Returns an empty clause set with the same named variables.
+func (cs *ClauseSet) copyVars() *ClauseSet {
+	r := &ClauseSet{
+		NumVars: cs.NumVars,
+		Index:   make(map[string]int),
+		Names:   append([]string(nil), cs.Names...),
+	}
+	for name, v := range cs.Index {
+		r.Index[name] = v
+	}
+	return r
+}
@@ -0,0 +1,90 @@
+package logic
+
+import (
+	"testing"
+)
+
+func TestEquivalent(t *testing.T) {
+	a, b := NewLeaf("a"), NewLeaf("b")
+	if ok, c := Equivalent(NewOperation(IF, a, b), NewOperation(OR, NewOperation(NOT, a), b)); !ok {
+		t.Fatalf("Implication not equivalent on %v", c)
+	}
+	l, r := NewOperation(IF, a, b), NewOperation(IF, b, a)
+	ok, c := Equivalent(l, r)
+	if ok || l.Eval(c) == r.Eval(c) {
+		t.Fatalf("Equivalent(%s, %s) returned %v, %v", l, r, ok, c)
+	}
+}
+
+func TestRewritesEquivalent(t *testing.T) {
+	// CNF grows exponentially, keep the formulas shallow
+	g := NewGenerator(1, GeneratorOptions{Depth: 3})
+	for i := 0; i < 300; i++ {
+		n := g.Formula()
+		rewrites := map[string]Node{
+			"Simplify": Simplify(n),
+			"DeMorgan": DeMorgan(n),
+			"CNF":      CNF(n),
+		}
+		for name, r := range rewrites {
+			if ok, c := Equivalent(n, r); !ok {
+				t.Fatalf("%s(%s) = %s differs on %v", name, n, r, c)
+			}
+		}
+	}
+}
+
+func TestTseitinEquisatisfiable(t *testing.T) {
+	g := NewGenerator(2, GeneratorOptions{Depth: 4})
+	for i := 0; i < 100; i++ {
+		n := g.Formula()
+		cs := TseitinClauses(n)
+		// Name the auxiliary variables to turn the clauses into a
+		// formula.
+		f := NewOperation(AND)
+		for _, c := range cs.Clauses {
+			or := NewOperation(OR)
+			for _, l := range c {
+				var leaf Node = NewLeaf(cs.Names[l.Var()])
+				if cs.Names[l.Var()] == "" {
+					leaf = NewLeaf("aux" + string(rune('0'+l.Var()%10)) + string(rune('a'+l.Var()/10)))
+				}
+				if l < 0 {
+					leaf = NewOperation(NOT, leaf)
+				}
+				or.PushOperands(leaf)
+			}
+			f.PushOperands(or)
+		}
+		shared := make([]string, 0)
+		for name := range DefaultMap(n) {
+			shared = append(shared, name)
+		}
+		if ok, c := Equisatisfiable(n, f, shared); !ok {
+			t.Fatalf("Tseitin encoding of %s not equisatisfiable on %v", n, c)
+		}
+		x := NewLeaf(shared[0])
+		unsatisfiable, _ := Equivalent(n, NewOperation(AND, x, NewOperation(NOT, x)))
+		if ok, _ := Equivalent(n, f); ok && len(cs.Names) > len(shared)+1 && !unsatisfiable {
+			// Free auxiliary variables make the encoding false
+			// somewhere a satisfiable formula is true.
+			t.Fatalf("Tseitin encoding of %s equivalent to it", n)
+		}
+	}
+}
+
+func TestEquisatisfiable(t *testing.T) {
+	a, b, x := NewLeaf("a"), NewLeaf("b"), NewLeaf("x")
+	// Over a, (a v x) ^ (!x v b) is satisfiable for every a, as is
+	// true-ish a v !a.
+	l := NewOperation(AND, NewOperation(OR, a, x), NewOperation(OR, NewOperation(NOT, x), b))
+	r := NewOperation(OR, a, NewOperation(NOT, a))
+	if ok, c := Equisatisfiable(l, r, []string{"a"}); !ok {
+		t.Fatalf("Equisatisfiable returned counterexample %v", c)
+	}
+	// Over a and b, the first is unsatisfiable for a = b = false.
+	ok, c := Equisatisfiable(l, r, []string{"a", "b"})
+	if ok || c["a"] || c["b"] {
+		t.Fatalf("Equisatisfiable returned %v, %v", ok, c)
+	}
+}
@@ -0,0 +1,306 @@
+package logic
+
+// Fragment is a class of clause sets which can be solved in
+// polynomial time.
+type Fragment int
+
+const (
+	General Fragment = iota
+	Horn
+	TwoCNF
+	RenamableHorn
+)
+
+func (f Fragment) String() string {
+	switch f {
+	case Horn:
+		return "Horn"
+	case TwoCNF:
+		return "2-CNF"
+	case RenamableHorn:
+		return "renamable Horn"
+	}
+	return "general"
+}
+
+// Removes tautologies and duplicate literals.
+func normalizeClauses(cs *ClauseSet) []Clause {
+	r := make([]Clause, 0, len(cs.Clauses))
+	for _, c := range cs.Clauses {
+		if c, tautology := normalize(c); !tautology {
+			r = append(r, c)
+		}
+	}
+	return r
+}
+
+func isHorn(clauses []Clause) bool {
+	for _, c := range clauses {
+		positive := 0
+		for _, l := range c {
+			if l > 0 {
+				positive++
+			}
+		}
+		if positive > 1 {
+			return false
+		}
+	}
+	return true
+}
+
+func is2CNF(clauses []Clause) bool {
+	for _, c := range clauses {
+		if len(c) > 2 {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns the variables to flip to make clauses Horn, or nil if
+// there is no such renaming. At most one literal of a clause may be
+// positive after renaming, which is a 2-CNF over the renaming (Lewis
+// 1978). A literal l ends up positive iff the literal l of the
+// renaming variables is false. Short clauses forbid every pair of
+// positive literals, longer ones use the sequential encoding of
+// Sinz (2005) with auxiliary variables s_i, "one of the first i+1
+// literals is positive", to stay linear in the clause length.
+func hornRenaming(clauses []Clause, nvars int) []bool {
+	constraints := make([]Clause, 0)
+	aux := nvars
+	for _, c := range clauses {
+		if len(c) <= 3 {
+			for i := range c {
+				for j := i + 1; j < len(c); j++ {
+					constraints = append(constraints, Clause{c[i], c[j]})
+				}
+			}
+			continue
+		}
+		first := Lit(aux + 1)
+		aux += len(c) - 1
+		for i, l := range c {
+			s := first + Lit(i)
+			if i < len(c)-1 {
+				constraints = append(constraints, Clause{l, s})
+			}
+			if i > 0 {
+				constraints = append(constraints, Clause{l, -(s - 1)})
+				if i < len(c)-1 {
+					constraints = append(constraints, Clause{-(s - 1), s})
+				}
+			}
+		}
+	}
+	renaming, ok := solve2SAT(constraints, aux)
+	if !ok {
+		return nil
+	}
+	return renaming[:nvars+1]
+}
+
+// DetectFragment returns the first of Horn, 2-CNF and renamable Horn
+// which cs belongs to, or General.
+func DetectFragment(cs *ClauseSet) Fragment {
+	clauses := normalizeClauses(cs)
+	switch {
+	case isHorn(clauses):
+		return Horn
+	case is2CNF(clauses):
+		return TwoCNF
+	case hornRenaming(clauses, cs.NumVars) != nil:
+		return RenamableHorn
+	}
+	return General
+}
+
+// SolveFragment detects the fragment of cs and solves it with linear
+// time unit resolution (Horn), strongly connected components
+// (2-CNF) or, for General, the CDCL solver.
+func SolveFragment(cs *ClauseSet) ([]bool, bool, Fragment) {
+	clauses := normalizeClauses(cs)
+	if isHorn(clauses) {
+		model, ok := solveHorn(clauses, cs.NumVars)
+		return model, ok, Horn
+	}
+	if is2CNF(clauses) {
+		model, ok := solve2SAT(clauses, cs.NumVars)
+		return model, ok, TwoCNF
+	}
+	if renaming := hornRenaming(clauses, cs.NumVars); renaming != nil {
+		renamed := make([]Clause, len(clauses))
+		for i, c := range clauses {
+			renamed[i] = make(Clause, len(c))
+			for j, l := range c {
+				if renaming[l.Var()] {
+					l = -l
+				}
+				renamed[i][j] = l
+			}
+		}
+		model, ok := solveHorn(renamed, cs.NumVars)
+		if ok {
+			for v := 1; v <= cs.NumVars; v++ {
+				model[v] = model[v] != renaming[v]
+			}
+		}
+		return model, ok, RenamableHorn
+	}
+	s := NewSolverFromClauses(cs)
+	if s.Solve() {
+		return s.Model(), true, General
+	}
+	return nil, false, General
+}
+
+// SolveHorn computes the minimal model of a Horn clause set by linear
+// time unit resolution (Dowling and Gallier). It panics if cs is not
+// Horn.
+func SolveHorn(cs *ClauseSet) ([]bool, bool) {
+	clauses := normalizeClauses(cs)
+	if !isHorn(clauses) {
+		panic("Clause set is not Horn")
+	}
+	return solveHorn(clauses, cs.NumVars)
+}
+
+func solveHorn(clauses []Clause, nvars int) ([]bool, bool) {
+	model := make([]bool, nvars+1)
+	// Number of body variables not yet true per clause.
+	open := make([]int, len(clauses))
+	head := make([]Lit, len(clauses))
+	bodies := make([][]int, nvars+1)
+	queue := make([]int, 0)
+	for i, c := range clauses {
+		for _, l := range c {
+			if l > 0 {
+				head[i] = l
+			} else {
+				open[i]++
+				bodies[l.Var()] = append(bodies[l.Var()], i)
+			}
+		}
+		if open[i] == 0 {
+			queue = append(queue, i)
+		}
+	}
+	for len(queue) > 0 {
+		i := queue[0]
+		queue = queue[1:]
+		if head[i] == 0 {
+			return nil, false
+		}
+		v := head[i].Var()
+		if model[v] {
+			continue
+		}
+		model[v] = true
+		for _, j := range bodies[v] {
+			open[j]--
+			if open[j] == 0 {
+				queue = append(queue, j)
+			}
+		}
+	}
+	return model, true
+}
+
+// Solve2SAT decides a 2-CNF by computing the strongly connected
+// components of its implication graph. It panics if cs has a clause
+// with more than two literals.
+func Solve2SAT(cs *ClauseSet) ([]bool, bool) {
+	clauses := normalizeClauses(cs)
+	if !is2CNF(clauses) {
+		panic("Clause set is not 2-CNF")
+	}
+	return solve2SAT(clauses, cs.NumVars)
+}
+
+func solve2SAT(clauses []Clause, nvars int) ([]bool, bool) {
+	n := 2 * (nvars + 1)
+	edges := make([][]int, n)
+	for _, c := range clauses {
+		switch len(c) {
+		case 0:
+			return nil, false
+		case 1:
+			edges[(-c[0]).code()] = append(edges[(-c[0]).code()], c[0].code())
+		case 2:
+			edges[(-c[0]).code()] = append(edges[(-c[0]).code()], c[1].code())
+			edges[(-c[1]).code()] = append(edges[(-c[1]).code()], c[0].code())
+		}
+	}
+
+	// Iterative Tarjan. Components are numbered in reverse
+	// topological order.
+	index := make([]int, n)
+	low := make([]int, n)
+	comp := make([]int, n)
+	onStack := make([]bool, n)
+	for i := range index {
+		index[i] = -1
+	}
+	stack := make([]int, 0)
+	type frame struct {
+		node, edge int
+	}
+	counter, ncomp := 0, 0
+	for start := 2; start < n; start++ {
+		if index[start] >= 0 {
+			continue
+		}
+		calls := []frame{{start, 0}}
+		index[start], low[start] = counter, counter
+		counter++
+		stack = append(stack, start)
+		onStack[start] = true
+		for len(calls) > 0 {
+			f := &calls[len(calls)-1]
+			if f.edge < len(edges[f.node]) {
+				next := edges[f.node][f.edge]
+				f.edge++
+				if index[next] < 0 {
+					index[next], low[next] = counter, counter
+					counter++
+					stack = append(stack, next)
+					onStack[next] = true
+					calls = append(calls, frame{next, 0})
+				} else if onStack[next] && index[next] < low[f.node] {
+					low[f.node] = index[next]
+				}
+				continue
+			}
+			node := f.node
+			calls = calls[:len(calls)-1]
+			if len(calls) > 0 {
+				parent := calls[len(calls)-1].node
+				if low[node] < low[parent] {
+					low[parent] = low[node]
+				}
+			}
+			if low[node] == index[node] {
+				for {
+					top := stack[len(stack)-1]
+					stack = stack[:len(stack)-1]
+					onStack[top] = false
+					comp[top] = ncomp
+					if top == node {
+						break
+					}
+				}
+				ncomp++
+			}
+		}
+	}
+
+	model := make([]bool, nvars+1)
+	for v := 1; v <= nvars; v++ {
+		pos, neg := comp[Lit(v).code()], comp[Lit(-v).code()]
+		if pos == neg {
+			return nil, false
+		}
+		model[v] = pos < neg
+	}
+	return model, true
+}
@@ -0,0 +1,130 @@
+package logic
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// Random clause set with at most maxpos positive literals per clause.
+func randomRestricted(rng *rand.Rand, nvars, nclauses, k, maxpos int) *ClauseSet {
+	cs := randomClauseSet(rng, nvars, 0, k)
+	for i := 0; i < nclauses; i++ {
+		c := make(Clause, 1+rng.Intn(k))
+		pos := 0
+		for j := range c {
+			c[j] = Lit(-(rng.Intn(nvars) + 1))
+			if pos < maxpos && rng.Intn(2) == 0 {
+				c[j] = -c[j]
+				pos++
+			}
+		}
+		cs.AddClause(c...)
+	}
+	return cs
+}
+
+func checkFragment(t *testing.T, cs *ClauseSet, expected Fragment) {
+	sat := false
+	allModels(cs, func([]bool) { sat = true })
+	if f := DetectFragment(cs); f != expected {
+		t.Fatalf("DetectFragment(%s) returned %s", cs.DIMACS(), f)
+	}
+	model, ok, f := SolveFragment(cs)
+	if f != expected || ok != sat {
+		t.Fatalf("SolveFragment(%s) returned %v, %s", cs.DIMACS(), ok, f)
+	}
+	if ok && !cs.Satisfies(model) {
+		t.Fatalf("Invalid model for %s", cs.DIMACS())
+	}
+}
+
+func TestHorn(t *testing.T) {
+	rng := rand.New(rand.NewSource(8))
+	for i := 0; i < 200; i++ {
+		checkFragment(t, randomRestricted(rng, 8, 5+rng.Intn(20), 4, 1), Horn)
+	}
+}
+
+func TestRenamableHorn(t *testing.T) {
+	rng := rand.New(rand.NewSource(9))
+	for i := 0; i < 200; i++ {
+		cs := randomRestricted(rng, 8, 5+rng.Intn(20), 4, 1)
+		// Flip a random set of variables
+		flip := make([]bool, cs.NumVars+1)
+		for v := range flip {
+			flip[v] = rng.Intn(2) == 0
+		}
+		for _, c := range cs.Clauses {
+			for j, l := range c {
+				if flip[l.Var()] {
+					c[j] = -l
+				}
+			}
+		}
+		expected := RenamableHorn
+		if isHorn(normalizeClauses(cs)) {
+			expected = Horn
+		} else if is2CNF(normalizeClauses(cs)) {
+			expected = TwoCNF
+		}
+		checkFragment(t, cs, expected)
+	}
+}
+
+// The sequential encoding of long clauses must accept the same clause
+// sets as forbidding every pair of positive literals.
+func TestHornRenamingLongClauses(t *testing.T) {
+	rng := rand.New(rand.NewSource(11))
+	for i := 0; i < 300; i++ {
+		cs := randomRestricted(rng, 10, 3+rng.Intn(6), 8, 1+rng.Intn(2))
+		clauses := normalizeClauses(cs)
+		pairs := make([]Clause, 0)
+		for _, c := range clauses {
+			for j := range c {
+				for k := j + 1; k < len(c); k++ {
+					pairs = append(pairs, Clause{c[j], c[k]})
+				}
+			}
+		}
+		_, expected := solve2SAT(pairs, cs.NumVars)
+		renaming := hornRenaming(clauses, cs.NumVars)
+		if (renaming != nil) != expected {
+			t.Fatalf("hornRenaming(%s) = %v, expected renamable %v", cs.DIMACS(), renaming, expected)
+		}
+		if renaming == nil {
+			continue
+		}
+		for _, c := range clauses {
+			positive := 0
+			for _, l := range c {
+				if (l > 0) != renaming[l.Var()] {
+					positive++
+				}
+			}
+			if positive > 1 {
+				t.Fatalf("Renaming %v leaves %v non-Horn", renaming, c)
+			}
+		}
+	}
+}
+
+func TestTwoCNF(t *testing.T) {
+	rng := rand.New(rand.NewSource(10))
+	for i := 0; i < 200; i++ {
+		cs := randomClauseSet(rng, 8, 5+rng.Intn(20), 2)
+		cs.AddClause(1, 2)
+		cs.AddClause(3, 4)
+		checkFragment(t, cs, TwoCNF)
+	}
+}
+
+func TestGeneral(t *testing.T) {
+	cs := NewClauseSet()
+	cs.AddClause(1, 2, 3)
+	cs.AddClause(-1, -2, -3)
+	cs.AddClause(1, -2, 3)
+	cs.AddClause(-1, 2, -3)
+	cs.AddClause(1, 2, -3)
+	cs.AddClause(-1, -2, 3)
+	checkFragment(t, cs, General)
+}
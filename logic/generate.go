@@ -0,0 +1,121 @@
+package logic
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+type GeneratorOptions struct {
+	// Number of distinct leafs, named x1 to xn (default 5).
+	Vars int
+	// Maximum depth of the tree. Leafs are at depth 0.
+	Depth int
+	// Number of operands of every operation, within the bounds of
+	// its operator. Zero means 1 and 3 respectively.
+	MinArity, MaxArity int
+	// Operators to choose from, all known operators if empty.
+	Operators []string
+	// Probability of a leaf above the maximum depth (default 0.25).
+	LeafProb float64
+}
+
+// Generator produces random formulas and clause sets for testing.
+// The same seed and options yield the same sequence.
+type Generator struct {
+	opts GeneratorOptions
+	rng  *rand.Rand
+}
+
+func NewGenerator(seed int64, opts GeneratorOptions) *Generator {
+	if opts.Vars == 0 {
+		opts.Vars = 5
+	}
+	if opts.MinArity == 0 {
+		opts.MinArity = 1
+	}
+	if opts.MaxArity == 0 {
+		opts.MaxArity = 3
+	}
+	if opts.MaxArity < opts.MinArity {
+		opts.MaxArity = opts.MinArity
+	}
+	if len(opts.Operators) == 0 {
+		opts.Operators = Operators()
+	}
+	if opts.LeafProb == 0 {
+		opts.LeafProb = 0.25
+	}
+	return &Generator{
+		opts: opts,
+		rng:  rand.New(rand.NewSource(seed)),
+	}
+}
+
+// VarName returns the name of the i-th (0-based) leaf.
+func VarName(i int) string {
+	return fmt.Sprintf("x%d", i+1)
+}
+
+// Formula returns a random formula which Eval accepts.
+func (g *Generator) Formula() Node {
+	return g.formula(g.opts.Depth)
+}
+
+func (g *Generator) formula(depth int) Node {
+	if depth <= 0 || g.rng.Float64() < g.opts.LeafProb {
+		return NewLeaf(VarName(g.rng.Intn(g.opts.Vars)))
+	}
+	o := NewOperation(g.opts.Operators[g.rng.Intn(len(g.opts.Operators))])
+	op := operators[o.Operator]
+	min, max := g.opts.MinArity, g.opts.MaxArity
+	if min < op.MinArity {
+		min = op.MinArity
+	}
+	if op.MaxArity != 0 && max > op.MaxArity {
+		max = op.MaxArity
+	}
+	if min > max {
+		min = max
+		if min < op.MinArity {
+			min, max = op.MinArity, op.MinArity
+		}
+	}
+	n := min + g.rng.Intn(max-min+1)
+	for i := 0; i < n; i++ {
+		o.PushOperands(g.formula(depth - 1))
+	}
+	return o
+}
+
+// KCNF returns a uniform random k-CNF over nvars variables with
+// ratio*nvars clauses. Every clause has k distinct variables, each
+// negated with probability 1/2. Around the ratio 4.26 random 3-CNFs
+// turn from mostly satisfiable to mostly unsatisfiable.
+func (g *Generator) KCNF(nvars, k int, ratio float64) *ClauseSet {
+	if k > nvars {
+		panic(fmt.Sprintf("Cannot pick %d distinct of %d variables", k, nvars))
+	}
+	cs := NewClauseSet()
+	for i := 0; i < nvars; i++ {
+		cs.Var(VarName(i))
+	}
+	nclauses := int(ratio*float64(nvars) + 0.5)
+	for i := 0; i < nclauses; i++ {
+		c := make(Clause, 0, k)
+	next:
+		for len(c) < k {
+			l := Lit(g.rng.Intn(nvars) + 1)
+			for _, x := range c {
+				if x.Var() == l.Var() {
+					continue next
+				}
+			}
+			if g.rng.Intn(2) == 0 {
+				l = -l
+			}
+			c = append(c, l)
+		}
+		cs.AddClause(c...)
+	}
+	return cs
+}
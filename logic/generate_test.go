@@ -0,0 +1,122 @@
+package logic
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// Checks the operator and arity bounds of every operation of n.
+func checkShape(t *testing.T, n Node, opts GeneratorOptions, depth int, seen map[string]bool) {
+	o, ok := n.(*Operation)
+	if !ok {
+		return
+	}
+	if depth == 0 {
+		t.Fatalf("Operation %s below maximum depth", o)
+	}
+	seen[o.Operator] = true
+	if _, e := operatorOf(o); e != nil {
+		t.Fatal(e)
+	}
+	op, _ := LookupOperator(o.Operator)
+	if op.MaxArity != 1 && op.MinArity <= opts.MaxArity &&
+		(len(o.Operands) < opts.MinArity || len(o.Operands) > opts.MaxArity) {
+		t.Fatalf("Operation %s has wrong arity", o)
+	}
+	for _, operand := range o.Operands {
+		checkShape(t, operand, opts, depth-1, seen)
+	}
+}
+
+func TestGenerator(t *testing.T) {
+	opts := GeneratorOptions{Vars: 3, Depth: 4, MinArity: 2, MaxArity: 4}
+	g, h := NewGenerator(1, opts), NewGenerator(1, opts)
+	seen := make(map[string]bool)
+	for i := 0; i < 200; i++ {
+		n := g.Formula()
+		if m := h.Formula(); !reflect.DeepEqual(n, m) {
+			t.Fatalf("Same seed generated %s and %s", n, m)
+		}
+		checkShape(t, n, opts, opts.Depth, seen)
+		for name := range DefaultMap(n) {
+			if name != VarName(0) && name != VarName(1) && name != VarName(2) {
+				t.Fatalf("Unexpected leaf %s", name)
+			}
+		}
+	}
+	for _, op := range Operators() {
+		if !seen[op] {
+			t.Fatalf("Operator %s never generated", op)
+		}
+	}
+}
+
+func TestKCNF(t *testing.T) {
+	g := NewGenerator(2, GeneratorOptions{})
+	cs := g.KCNF(20, 3, 4.26)
+	if cs.NumVars != 20 || len(cs.Clauses) != 85 {
+		t.Fatalf("Got %d variables and %d clauses", cs.NumVars, len(cs.Clauses))
+	}
+	for _, c := range cs.Clauses {
+		if len(c) != 3 || c[0].Var() == c[1].Var() || c[0].Var() == c[2].Var() || c[1].Var() == c[2].Var() {
+			t.Fatalf("Clause %s is not made of 3 distinct variables", c)
+		}
+	}
+}
+
+// Evaluates every form of the formula on every configuration of its
+// leafs.
+func FuzzRewrites(f *testing.F) {
+	for seed := int64(0); seed < 8; seed++ {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, seed int64) {
+		// CNF grows exponentially, keep the formulas shallow
+		n := NewGenerator(seed, GeneratorOptions{Vars: 4, Depth: 3}).Formula()
+		forms := map[string]Node{
+			"Simplify": Simplify(n),
+			"DeMorgan": DeMorgan(n),
+			"CNF":      CNF(n),
+		}
+		vars := make([]string, 0)
+		for name := range DefaultMap(n) {
+			vars = append(vars, name)
+		}
+		sort.Strings(vars)
+		for a := 0; a < 1<<uint(len(vars)); a++ {
+			c := configuration(vars, a)
+			expected := n.Eval(c)
+			for name, r := range forms {
+				if r.Eval(c) != expected {
+					t.Fatalf("%s(%s) = %s differs on %v", name, n, r, c)
+				}
+			}
+		}
+	})
+}
+
+// Compares the solver on random 3-CNFs with enumerating all
+// assignments.
+func FuzzKCNF(f *testing.F) {
+	for seed := int64(0); seed < 8; seed++ {
+		f.Add(seed, 4.26)
+	}
+	f.Fuzz(func(t *testing.T, seed int64, ratio float64) {
+		if ratio < 0 || ratio > 10 {
+			return
+		}
+		cs := NewGenerator(seed, GeneratorOptions{}).KCNF(10, 3, ratio)
+		satisfiable := false
+		allModels(cs, func([]bool) {
+			satisfiable = true
+		})
+		s := NewSolverFromClauses(cs)
+		if s.Solve() != satisfiable {
+			t.Fatalf("Solver returned %v for %v", !satisfiable, cs.Clauses)
+		}
+		if satisfiable && !cs.Satisfies(s.Model()) {
+			t.Fatalf("Solver model does not satisfy %v", cs.Clauses)
+		}
+	})
+}
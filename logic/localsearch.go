@@ -0,0 +1,219 @@
+package logic
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+type LocalSearchAlgorithm int
+
+const (
+	ProbSAT LocalSearchAlgorithm = iota
+	WalkSAT
+)
+
+type LocalSearchOptions struct {
+	Algorithm LocalSearchAlgorithm
+	Seed      int64
+	// ProbSAT: base of the polynomial break distribution (default
+	// 2.3). WalkSAT: probability of a random walk step (default
+	// 0.567).
+	Noise float64
+	// Flips per try before restarting from a random assignment and
+	// number of tries. Zero means 100 * number of variables flips
+	// and unlimited tries.
+	MaxFlips int
+	MaxTries int
+}
+
+type localSearch struct {
+	opts     LocalSearchOptions
+	rng      *rand.Rand
+	clauses  []Clause
+	occurs   [][]int
+	assign   []bool
+	numTrue  []int
+	unsat    []int
+	unsatPos []int
+	probs    []float64
+}
+
+// LocalSearch looks for a model of cs by stochastic local search.
+// It gives up after the configured number of tries or once ctx is
+// done, so a false result does not mean cs is unsatisfiable.
+func LocalSearch(ctx context.Context, cs *ClauseSet, opts LocalSearchOptions) ([]bool, bool) {
+	if opts.Noise == 0 {
+		opts.Noise = 2.3
+		if opts.Algorithm == WalkSAT {
+			opts.Noise = 0.567
+		}
+	}
+	if opts.MaxFlips == 0 {
+		opts.MaxFlips = 100 * (cs.NumVars + 1)
+	}
+	ls := &localSearch{
+		opts:   opts,
+		rng:    rand.New(rand.NewSource(opts.Seed)),
+		occurs: make([][]int, 2*(cs.NumVars+1)),
+		assign: make([]bool, cs.NumVars+1),
+	}
+	for _, c := range cs.Clauses {
+		c, tautology := normalize(c)
+		if tautology {
+			continue
+		}
+		if len(c) == 0 {
+			return nil, false
+		}
+		for _, l := range c {
+			ls.occurs[l.code()] = append(ls.occurs[l.code()], len(ls.clauses))
+		}
+		ls.clauses = append(ls.clauses, c)
+	}
+	ls.numTrue = make([]int, len(ls.clauses))
+	ls.unsatPos = make([]int, len(ls.clauses))
+
+	for try := 0; opts.MaxTries == 0 || try < opts.MaxTries; try++ {
+		ls.randomize()
+		for flip := 0; flip < opts.MaxFlips; flip++ {
+			if len(ls.unsat) == 0 {
+				return ls.assign, true
+			}
+			if flip%1024 == 0 && ctx.Err() != nil {
+				return nil, false
+			}
+			c := ls.clauses[ls.unsat[ls.rng.Intn(len(ls.unsat))]]
+			ls.flip(ls.pick(c).Var())
+		}
+		if len(ls.unsat) == 0 {
+			return ls.assign, true
+		}
+		if ctx.Err() != nil {
+			return nil, false
+		}
+	}
+	return nil, false
+}
+
+// Sorts and dedupes the literals of c.
+func normalize(c Clause) (Clause, bool) {
+	sorted := append(Clause(nil), c...)
+	sort.Sort(litSlice(sorted))
+	r := make(Clause, 0, len(sorted))
+	for i, l := range sorted {
+		if i > 0 && sorted[i-1] == l {
+			continue
+		}
+		if i > 0 && sorted[i-1] == -l {
+			return nil, true
+		}
+		r = append(r, l)
+	}
+	return r, false
+}
+
+func (ls *localSearch) isTrue(l Lit) bool {
+	return ls.assign[l.Var()] == (l > 0)
+}
+
+func (ls *localSearch) randomize() {
+	for v := 1; v < len(ls.assign); v++ {
+		ls.assign[v] = ls.rng.Intn(2) == 1
+	}
+	ls.unsat = ls.unsat[:0]
+	for i, c := range ls.clauses {
+		ls.numTrue[i] = 0
+		for _, l := range c {
+			if ls.isTrue(l) {
+				ls.numTrue[i]++
+			}
+		}
+		if ls.numTrue[i] == 0 {
+			ls.unsatPos[i] = len(ls.unsat)
+			ls.unsat = append(ls.unsat, i)
+		}
+	}
+}
+
+// Number of clauses which become false when v is flipped.
+func (ls *localSearch) breakCount(v int) int {
+	l := Lit(v)
+	if !ls.assign[v] {
+		l = -l
+	}
+	n := 0
+	for _, i := range ls.occurs[l.code()] {
+		if ls.numTrue[i] == 1 {
+			n++
+		}
+	}
+	return n
+}
+
+// Chooses the literal of the unsatisfied clause c to flip.
+func (ls *localSearch) pick(c Clause) Lit {
+	if ls.opts.Algorithm == WalkSAT {
+		best, bestBreak := Lit(0), -1
+		for _, l := range c {
+			b := ls.breakCount(l.Var())
+			if b == 0 {
+				return l
+			}
+			if bestBreak < 0 || b < bestBreak {
+				best, bestBreak = l, b
+			}
+		}
+		if ls.rng.Float64() < ls.opts.Noise {
+			return c[ls.rng.Intn(len(c))]
+		}
+		return best
+	}
+
+	ls.probs = ls.probs[:0]
+	sum := 0.0
+	for _, l := range c {
+		p := math.Pow(1+float64(ls.breakCount(l.Var())), -ls.opts.Noise)
+		ls.probs = append(ls.probs, p)
+		sum += p
+	}
+	x := ls.rng.Float64() * sum
+	for i, p := range ls.probs {
+		if x < p {
+			return c[i]
+		}
+		x -= p
+	}
+	return c[len(c)-1]
+}
+
+func (ls *localSearch) flip(v int) {
+	ls.assign[v] = !ls.assign[v]
+	l := Lit(v)
+	if !ls.assign[v] {
+		l = -l
+	}
+	// l became true, -l became false
+	for _, i := range ls.occurs[l.code()] {
+		ls.numTrue[i]++
+		if ls.numTrue[i] == 1 {
+			ls.removeUnsat(i)
+		}
+	}
+	for _, i := range ls.occurs[(-l).code()] {
+		ls.numTrue[i]--
+		if ls.numTrue[i] == 0 {
+			ls.unsatPos[i] = len(ls.unsat)
+			ls.unsat = append(ls.unsat, i)
+		}
+	}
+}
+
+func (ls *localSearch) removeUnsat(i int) {
+	pos := ls.unsatPos[i]
+	last := ls.unsat[len(ls.unsat)-1]
+	ls.unsat[pos] = last
+	ls.unsatPos[last] = pos
+	ls.unsat = ls.unsat[:len(ls.unsat)-1]
+}
@@ -0,0 +1,58 @@
+package logic
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func TestLocalSearch(t *testing.T) {
+	rng := rand.New(rand.NewSource(7))
+	for _, algorithm := range []LocalSearchAlgorithm{ProbSAT, WalkSAT} {
+		found := 0
+		for i := 0; i < 50; i++ {
+			// Random 3-CNF with 40 variables at ratio 3.5, as formula
+			n := NewOperation(AND)
+			for j := 0; j < 140; j++ {
+				or := NewOperation(OR)
+				for k := 0; k < 3; k++ {
+					var l Node = NewLeaf(fmt.Sprintf("x%d", rng.Intn(40)))
+					if rng.Intn(2) == 0 {
+						l = NewOperation(NOT, l)
+					}
+					or.PushOperands(l)
+				}
+				n.PushOperands(or)
+			}
+			n.PushOperands(NewOperation(IFF, NewLeaf("x1"), NewOperation(OR, NewLeaf("x2"), NewLeaf("x3"))))
+			cs := Clauses(n)
+			model, ok := LocalSearch(context.Background(), cs, LocalSearchOptions{
+				Algorithm: algorithm,
+				Seed:      int64(i),
+				MaxTries:  10,
+			})
+			if !ok {
+				continue
+			}
+			found++
+			if !n.Eval(cs.Configuration(model)) {
+				t.Fatalf("Model does not satisfy %s", n)
+			}
+		}
+		if found < 25 {
+			t.Fatalf("Only %d models found with algorithm %d", found, algorithm)
+		}
+	}
+}
+
+func TestLocalSearchUnsatisfiable(t *testing.T) {
+	cs := pigeonhole(4, 3)
+	if _, ok := LocalSearch(context.Background(), cs, LocalSearchOptions{MaxTries: 3}); ok {
+		t.Fatalf("Model found for unsatisfiable formula")
+	}
+	r, e := SolvePortfolio(context.Background(), cs, DefaultPortfolio(4))
+	if e != nil || r.Satisfiable {
+		t.Fatalf("SolvePortfolio returned %v, %s", r.Satisfiable, e)
+	}
+}
@@ -1,9 +1,5 @@
 package logic
 
-import (
-	"fmt"
-)
-
 const (
 	NOT = "!"
 	AND = "^"
@@ -12,16 +8,6 @@ const (
 	IF  = "=>"
 )
 
-var (
-	opFuncMap = map[string]opFunc{
-		NOT: not,
-		AND: and,
-		OR:  or,
-		IFF: iff,
-		IF:  _if,
-	}
-)
-
 type Node interface {
 	Eval(Configuration) bool
 	String() string
@@ -58,8 +44,10 @@ func NewOperation(opidx string, ops ...Node) *Operation {
 	return op
 }
 
+// Eval panics if the operator is unknown or has the wrong number of
+// operands.
 func (o *Operation) Eval(config Configuration) bool {
-	return opFuncMap[o.Operator](o.Operands, config)
+	return mustOperatorOf(o).Eval(o.Operands, config)
 }
 
 func (o *Operation) PushOperands(n ...Node) {
@@ -81,8 +69,6 @@ func (o *Operation) String() string {
 	return r + ")"
 }
 
-type opFunc func([]Node, Configuration) bool
-
 type Leaf string
 
 func (l Leaf) Eval(config Configuration) bool {
@@ -100,16 +86,10 @@ func NewLeaf(name string) Node {
 type Configuration map[string]bool
 
 func not(operands []Node, config Configuration) bool {
-	if len(operands) != 1 {
-		panic("`not` only takes 1 argument")
-	}
 	return !operands[0].Eval(config)
 }
 
 func or(operands []Node, config Configuration) bool {
-	if len(operands) == 0 {
-		panic("Zero arguments for `or`")
-	}
 	for _, operand := range operands {
 		if operand.Eval(config) {
 			return true
@@ -119,9 +99,6 @@ func or(operands []Node, config Configuration) bool {
 }
 
 func and(operands []Node, config Configuration) bool {
-	if len(operands) == 0 {
-		panic("Zero arguments for `and`")
-	}
 	for _, operand := range operands {
 		if !operand.Eval(config) {
 			return false
@@ -131,9 +108,6 @@ func and(operands []Node, config Configuration) bool {
 }
 
 func _if(operands []Node, config Configuration) bool {
-	if len(operands) == 0 {
-		panic("Zero arguments for `if`")
-	}
 	r := true
 	for _, operand := range operands {
 		op := operand.Eval(config)
@@ -143,9 +117,6 @@ func _if(operands []Node, config Configuration) bool {
 }
 
 func iff(operands []Node, config Configuration) bool {
-	if len(operands) == 0 {
-		panic("Zero arguments for `iff`")
-	}
 	r := true
 	for _, operand := range operands {
 		op := operand.Eval(config)
@@ -154,42 +125,7 @@ func iff(operands []Node, config Configuration) bool {
 	return r
 }
 
-var (
-	idxmap map[string]int
-)
-
 func FormatSAT(n Node) (string, map[string]int) {
-	idxmap = make(map[string]int)
-	n = CNF(n)
-	sat := formatSAT(n)
-	return fmt.Sprintf("p cnf %d %d\n%s", len(idxmap), len(n.(*Operation).Operands), sat), idxmap
-}
-
-func formatSAT(n Node) string {
-	s := ""
-	if _, ok := n.(Leaf); ok {
-		name := n.String()
-		if _, ok := idxmap[name]; !ok {
-			idxmap[name] = len(idxmap) + 1
-		}
-		return fmt.Sprintf("%d", idxmap[name])
-	}
-	x := n.(*Operation)
-	switch x.Operator {
-	case AND:
-		for _, op := range x.Operands {
-			s += formatSAT(op) + " 0 \n"
-		}
-	case OR:
-		sep := ""
-		for _, op := range x.Operands {
-			s += sep + formatSAT(op)
-			sep = " "
-		}
-	case NOT:
-		s = "-" + formatSAT(x.Operands[0])
-	default:
-		panic("This is not possibe o_O")
-	}
-	return s
+	cs := Clauses(n)
+	return cs.DIMACS(), cs.Index
 }
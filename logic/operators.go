@@ -0,0 +1,292 @@
+package logic
+
+import (
+	"fmt"
+	"sort"
+)
+
+const (
+	XOR  = "xor"
+	NAND = "nand"
+	NOR  = "nor"
+	ITE  = "ite"
+)
+
+// Operator describes an operator of Operation. Everything beyond
+// Eval works on the core operators AND, OR and NOT, so other
+// operators have to say how to express themselves in those.
+type Operator struct {
+	// Symbol is the value of Operation.Operator, Name is used in
+	// error messages.
+	Symbol, Name string
+	// Allowed numbers of operands. MaxArity 0 means unbounded.
+	MinArity, MaxArity int
+	// Eval is called with the number of operands already checked.
+	Eval func(operands []Node, config Configuration) bool
+	// Rewrite returns an equivalent formula over the given operands
+	// using other operators which can be rewritten in turn. It is nil
+	// only for AND, OR and NOT.
+	Rewrite func(operands []Node) Node
+	// Encode is optional. It adds clauses to cs and returns a literal
+	// equivalent to the operation on the given operand literals,
+	// usually a fresh one from cs.NewVar. Without it, Tseitin
+	// encodes the rewritten formula.
+	Encode func(cs *ClauseSet, ins []Lit) Lit
+}
+
+var operators = make(map[string]*Operator)
+
+func init() {
+	for _, op := range []*Operator{
+		{Symbol: NOT, Name: "not", MinArity: 1, MaxArity: 1, Eval: not, Encode: encodeNot},
+		{Symbol: AND, Name: "and", MinArity: 1, Eval: and, Encode: encodeAnd},
+		{Symbol: OR, Name: "or", MinArity: 1, Eval: or, Encode: encodeOr},
+		{Symbol: IF, Name: "if", MinArity: 1, Eval: _if, Rewrite: rewriteIf, Encode: encodeIf},
+		{Symbol: IFF, Name: "iff", MinArity: 1, Eval: iff, Rewrite: rewriteIff, Encode: encodeIff},
+		{Symbol: XOR, Name: "xor", MinArity: 1, Eval: xor, Rewrite: rewriteXor, Encode: encodeXor},
+		{Symbol: NAND, Name: "nand", MinArity: 1, Eval: nand, Rewrite: rewriteNand, Encode: encodeNand},
+		{Symbol: NOR, Name: "nor", MinArity: 1, Eval: nor, Rewrite: rewriteNor, Encode: encodeNor},
+		{Symbol: ITE, Name: "ite", MinArity: 3, MaxArity: 3, Eval: ite, Rewrite: rewriteIte, Encode: encodeIte},
+	} {
+		RegisterOperator(op)
+	}
+}
+
+// RegisterOperator makes op known to every function of the package,
+// replacing an earlier operator with the same symbol. It is meant
+// to be called from init functions and is not safe for concurrent
+// use with anything else of the package.
+func RegisterOperator(op *Operator) {
+	if op.Symbol == "" || op.Eval == nil {
+		panic("Operator needs a symbol and Eval")
+	}
+	if op.Rewrite == nil && op.Symbol != AND && op.Symbol != OR && op.Symbol != NOT {
+		panic(fmt.Sprintf("Operator %s needs Rewrite", op.Symbol))
+	}
+	if op.Name == "" {
+		op.Name = op.Symbol
+	}
+	operators[op.Symbol] = op
+}
+
+// LookupOperator returns the registered operator with the given
+// symbol.
+func LookupOperator(symbol string) (*Operator, bool) {
+	op, ok := operators[symbol]
+	return op, ok
+}
+
+// Operators returns the symbols of all registered operators, sorted.
+func Operators() []string {
+	r := make([]string, 0, len(operators))
+	for symbol := range operators {
+		r = append(r, symbol)
+	}
+	sort.Strings(r)
+	return r
+}
+
+// Returns the operator of o, or an error if it is unknown or gets the
+// wrong number of operands.
+func operatorOf(o *Operation) (*Operator, error) {
+	op, ok := operators[o.Operator]
+	if !ok {
+		return nil, fmt.Errorf("Unknown operator `%s`", o.Operator)
+	}
+	if op.MinArity == op.MaxArity && len(o.Operands) != op.MinArity {
+		s := "s"
+		if op.MinArity == 1 {
+			s = ""
+		}
+		return nil, fmt.Errorf("`%s` only takes %d argument%s", op.Name, op.MinArity, s)
+	}
+	if len(o.Operands) == 0 {
+		return nil, fmt.Errorf("Zero arguments for `%s`", op.Name)
+	}
+	if len(o.Operands) < op.MinArity {
+		return nil, fmt.Errorf("`%s` takes at least %d arguments", op.Name, op.MinArity)
+	}
+	if op.MaxArity != 0 && len(o.Operands) > op.MaxArity {
+		return nil, fmt.Errorf("`%s` takes at most %d arguments", op.Name, op.MaxArity)
+	}
+	return op, nil
+}
+
+// Returns the operator of o, allowing AND and OR without operands as
+// the constants true and false, else panics like Eval.
+func mustOperatorOrConstant(o *Operation) *Operator {
+	if isConstant(o) {
+		return operators[o.Operator]
+	}
+	return mustOperatorOf(o)
+}
+
+// Like operatorOf, but panics like Eval.
+func mustOperatorOf(o *Operation) *Operator {
+	op, e := operatorOf(o)
+	if e != nil {
+		panic(e.Error())
+	}
+	return op
+}
+
+func xor(operands []Node, config Configuration) bool {
+	r := false
+	for _, operand := range operands {
+		r = r != operand.Eval(config)
+	}
+	return r
+}
+
+func nand(operands []Node, config Configuration) bool {
+	return !and(operands, config)
+}
+
+func nor(operands []Node, config Configuration) bool {
+	return !or(operands, config)
+}
+
+func ite(operands []Node, config Configuration) bool {
+	if operands[0].Eval(config) {
+		return operands[1].Eval(config)
+	}
+	return operands[2].Eval(config)
+}
+
+// The rewrites fold from the left like Eval. Operands are shared,
+// not copied.
+
+func rewriteIf(operands []Node) Node {
+	// a -> b <=> (!a v b)
+	r := operands[0]
+	for _, operand := range operands[1:] {
+		r = NewOperation(OR, NewOperation(NOT, r), operand)
+	}
+	return r
+}
+
+func rewriteIff(operands []Node) Node {
+	// a <-> b <=> (!a v b) ^ (!b v a)
+	r := operands[0]
+	for _, operand := range operands[1:] {
+		r = NewOperation(AND,
+			NewOperation(OR, NewOperation(NOT, r), operand),
+			NewOperation(OR, NewOperation(NOT, operand), r))
+	}
+	return r
+}
+
+func rewriteXor(operands []Node) Node {
+	// a xor b <=> (a v b) ^ (!a v !b)
+	r := operands[0]
+	for _, operand := range operands[1:] {
+		r = NewOperation(AND,
+			NewOperation(OR, r, operand),
+			NewOperation(OR, NewOperation(NOT, r), NewOperation(NOT, operand)))
+	}
+	return r
+}
+
+func rewriteNand(operands []Node) Node {
+	return NewOperation(NOT, NewOperation(AND, operands...))
+}
+
+func rewriteNor(operands []Node) Node {
+	return NewOperation(NOT, NewOperation(OR, operands...))
+}
+
+func rewriteIte(operands []Node) Node {
+	// c ? t : e <=> (!c v t) ^ (c v e)
+	c, t, e := operands[0], operands[1], operands[2]
+	return NewOperation(AND,
+		NewOperation(OR, NewOperation(NOT, c), t),
+		NewOperation(OR, c, e))
+}
+
+func encodeNot(cs *ClauseSet, ins []Lit) Lit {
+	return -ins[0]
+}
+
+// Returns a new literal equivalent to the conjunction of ins.
+func encodeAnd(cs *ClauseSet, ins []Lit) Lit {
+	if len(ins) == 1 {
+		return ins[0]
+	}
+	out := cs.NewVar()
+	long := Clause{out}
+	for _, in := range ins {
+		cs.AddClause(-out, in)
+		long = append(long, -in)
+	}
+	cs.AddClause(long...)
+	return out
+}
+
+func encodeOr(cs *ClauseSet, ins []Lit) Lit {
+	return -encodeAnd(cs, negate(ins))
+}
+
+func encodeIf(cs *ClauseSet, ins []Lit) Lit {
+	// r = !r || x, starting with r = x1
+	out := ins[0]
+	for _, in := range ins[1:] {
+		out = -encodeAnd(cs, []Lit{out, -in})
+	}
+	return out
+}
+
+func encodeIff(cs *ClauseSet, ins []Lit) Lit {
+	out := ins[0]
+	for _, in := range ins[1:] {
+		out = encodeEqual(cs, out, in)
+	}
+	return out
+}
+
+func encodeXor(cs *ClauseSet, ins []Lit) Lit {
+	out := ins[0]
+	for _, in := range ins[1:] {
+		out = -encodeEqual(cs, out, in)
+	}
+	return out
+}
+
+func encodeNand(cs *ClauseSet, ins []Lit) Lit {
+	return -encodeAnd(cs, ins)
+}
+
+func encodeNor(cs *ClauseSet, ins []Lit) Lit {
+	return encodeAnd(cs, negate(ins))
+}
+
+func encodeIte(cs *ClauseSet, ins []Lit) Lit {
+	c, t, e := ins[0], ins[1], ins[2]
+	out := cs.NewVar()
+	cs.AddClause(-out, -c, t)
+	cs.AddClause(-out, c, e)
+	cs.AddClause(out, -c, -t)
+	cs.AddClause(out, c, -e)
+	// Redundant, but lets propagation see that equal branches decide
+	// the value.
+	cs.AddClause(-out, t, e)
+	cs.AddClause(out, -t, -e)
+	return out
+}
+
+// Returns a new literal equivalent to a <=> b.
+func encodeEqual(cs *ClauseSet, a, b Lit) Lit {
+	out := cs.NewVar()
+	cs.AddClause(-out, -a, b)
+	cs.AddClause(-out, a, -b)
+	cs.AddClause(out, a, b)
+	cs.AddClause(out, -a, -b)
+	return out
+}
+
+func negate(lits []Lit) []Lit {
+	r := make([]Lit, len(lits))
+	for i, l := range lits {
+		r[i] = -l
+	}
+	return r
+}
@@ -0,0 +1,120 @@
+package logic
+
+import (
+	"testing"
+)
+
+func TestBuiltinOperators(t *testing.T) {
+	a, b, c := NewLeaf("a"), NewLeaf("b"), NewLeaf("c")
+	vars := []string{"a", "b", "c"}
+	cases := []struct {
+		n Node
+		f func(a, b, c bool) bool
+	}{
+		{NewOperation(XOR, a, b, c), func(a, b, c bool) bool { return a != b != c }},
+		{NewOperation(NAND, a, b, c), func(a, b, c bool) bool { return !(a && b && c) }},
+		{NewOperation(NOR, a, b, c), func(a, b, c bool) bool { return !(a || b || c) }},
+		{NewOperation(ITE, a, b, c), func(a, b, c bool) bool { return a && b || !a && c }},
+	}
+	for _, x := range cases {
+		for i := 0; i < 8; i++ {
+			config := configuration(vars, i)
+			if x.n.Eval(config) != x.f(config["a"], config["b"], config["c"]) {
+				t.Fatalf("%s is wrong on %v", x.n, config)
+			}
+		}
+	}
+}
+
+func TestOperatorArity(t *testing.T) {
+	a := NewLeaf("a")
+	for _, n := range []*Operation{
+		NewOperation(ITE, a, a),
+		NewOperation(XOR),
+		NewOperation("?", a),
+	} {
+		if _, e := operatorOf(n); e == nil {
+			t.Fatalf("%s accepted", n)
+		}
+	}
+}
+
+// Checks every rewrite and encoding of n against Eval.
+func checkPipeline(t *testing.T, n Node) {
+	for name, r := range map[string]Node{
+		"Simplify": Simplify(n),
+		"DeMorgan": DeMorgan(n),
+		"CNF":      CNF(n),
+	} {
+		if ok, c := Equivalent(n, r); !ok {
+			t.Fatalf("%s(%s) = %s differs on %v", name, n, r, c)
+		}
+	}
+	p, e := Compile(n)
+	if e != nil {
+		t.Fatal(e)
+	}
+	table := p.TruthTable()
+	cs := Clauses(n)
+	for a := 0; a < 1<<uint(len(p.Vars)); a++ {
+		c := configuration(p.Vars, a)
+		expected := n.Eval(c)
+		if (table[a/64]&(1<<uint(a%64)) != 0) != expected {
+			t.Fatalf("Program of %s is wrong on %v", n, c)
+		}
+		model := make([]bool, cs.NumVars+1)
+		for name, v := range cs.Index {
+			model[v] = c[name]
+		}
+		if cs.Satisfies(model) != expected {
+			t.Fatalf("Clauses of %s are wrong on %v", n, c)
+		}
+	}
+}
+
+func TestOperatorPipeline(t *testing.T) {
+	g := NewGenerator(3, GeneratorOptions{Vars: 4, Depth: 3})
+	for i := 0; i < 200; i++ {
+		checkPipeline(t, g.Formula())
+	}
+}
+
+func TestUserOperator(t *testing.T) {
+	// Majority of three, without a clause encoding of its own
+	RegisterOperator(&Operator{
+		Symbol:   "maj",
+		MinArity: 3,
+		MaxArity: 3,
+		Eval: func(operands []Node, config Configuration) bool {
+			n := 0
+			for _, operand := range operands {
+				if operand.Eval(config) {
+					n++
+				}
+			}
+			return n >= 2
+		},
+		Rewrite: func(operands []Node) Node {
+			a, b, c := operands[0], operands[1], operands[2]
+			return NewOperation(OR, NewOperation(AND, a, b), NewOperation(AND, a, c), NewOperation(AND, b, c))
+		},
+	})
+	a, b, c, d := NewLeaf("a"), NewLeaf("b"), NewLeaf("c"), NewLeaf("d")
+	n := NewOperation(XOR, NewOperation("maj", a, b, NewOperation(NOT, c)), NewOperation(ITE, d, a, NewOperation("maj", b, c, d)))
+	checkPipeline(t, n)
+	if ok, c := Equivalent(NewOperation("maj", a, b, c), NewOperation("maj", c, b, a)); !ok {
+		t.Fatalf("Majority not symmetric on %v", c)
+	}
+}
+
+func TestRegisterOperatorWithoutRewrite(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Operator without Rewrite accepted")
+		}
+		if _, ok := LookupOperator("min"); ok {
+			t.Fatal("Rejected operator registered")
+		}
+	}()
+	RegisterOperator(&Operator{Symbol: "min", Eval: and})
+}
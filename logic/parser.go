@@ -9,7 +9,7 @@ func Parse(s string) (Node, error) {
 	panic("not implemented")
 	var n Node
 	s = strings.TrimSpace(s)
-	for opstring := range opFuncMap {
+	for opstring := range operators {
 		if strings.HasPrefix(s, opstring) {
 			n = &Operation{
 				Operator: opstring,
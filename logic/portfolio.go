@@ -0,0 +1,141 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// Learnt clauses up to this length are shared between the members
+// of a portfolio.
+const shareLimit = 8
+
+type PortfolioResult struct {
+	Satisfiable bool
+	Model       []bool
+	// Index of the configuration which finished first.
+	Winner int
+}
+
+// Collects short learnt clauses of all members. Every member reads
+// the clauses of the others from its own cursor on.
+type clauseExchange struct {
+	mu      sync.Mutex
+	clauses [][]Lit
+	origin  []int
+}
+
+func (x *clauseExchange) put(from int, lits []Lit) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.clauses = append(x.clauses, lits)
+	x.origin = append(x.origin, from)
+}
+
+func (x *clauseExchange) get(to int, cursor *int) [][]Lit {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	r := make([][]Lit, 0)
+	for ; *cursor < len(x.clauses); *cursor++ {
+		if x.origin[*cursor] != to {
+			r = append(r, x.clauses[*cursor])
+		}
+	}
+	return r
+}
+
+// DefaultPortfolio returns n configurations differing in seed,
+// restart policy, initial phase, random decisions and preprocessing.
+// The fourth member runs probSAT.
+func DefaultPortfolio(n int) []SolverOptions {
+	r := make([]SolverOptions, n)
+	for i := range r {
+		r[i] = SolverOptions{
+			Seed:       int64(i),
+			Restarts:   RestartPolicy(i % 3),
+			Phase:      Phase((i / 3) % 3),
+			Preprocess: i%2 == 1,
+		}
+		if i >= 3 {
+			r[i].RandomFreq = 0.02
+		}
+		if i == 3 {
+			r[i].LocalSearch = &LocalSearchOptions{
+				Algorithm: ProbSAT,
+				Seed:      int64(i),
+			}
+		}
+	}
+	return r
+}
+
+// SolvePortfolio runs one solver per configuration on cs in parallel.
+// The members share short learnt clauses. As soon as one of them
+// finishes, the others are cancelled. An error is returned if no
+// member finished, usually because parent is done.
+func SolvePortfolio(parent context.Context, cs *ClauseSet, configs []SolverOptions) (PortfolioResult, error) {
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+
+	x := &clauseExchange{}
+	results := make(chan PortfolioResult, len(configs))
+	var wg sync.WaitGroup
+	for i, opts := range configs {
+		wg.Add(1)
+		go func(i int, opts SolverOptions) {
+			defer wg.Done()
+			if opts.LocalSearch != nil {
+				if model, ok := LocalSearch(ctx, cs, *opts.LocalSearch); ok {
+					results <- PortfolioResult{
+						Satisfiable: true,
+						Model:       model,
+						Winner:      i,
+					}
+				}
+				return
+			}
+			input := cs
+			if opts.Preprocess {
+				input = Preprocess(cs)
+			}
+			s := NewSolverWithOptions(input.NumVars, opts)
+			cursor := 0
+			s.Export = func(lits []Lit) {
+				if len(lits) <= shareLimit {
+					x.put(i, lits)
+				}
+			}
+			s.Import = func() [][]Lit {
+				return x.get(i, &cursor)
+			}
+			for _, c := range input.Clauses {
+				s.AddClause(c...)
+			}
+			sat, err := s.SolveContext(ctx)
+			if err != nil {
+				return
+			}
+			results <- PortfolioResult{
+				Satisfiable: sat,
+				Model:       s.Model(),
+				Winner:      i,
+			}
+		}(i, opts)
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	r, ok := <-results
+	cancel()
+	for range results {
+	}
+	if !ok && parent.Err() != nil {
+		return r, parent.Err()
+	}
+	if !ok {
+		return r, errors.New("No portfolio member reached a result")
+	}
+	return r, nil
+}
@@ -0,0 +1,56 @@
+package logic
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestPreprocess(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	for i := 0; i < 200; i++ {
+		cs := randomClauseSet(rng, 8, 5+rng.Intn(30), 1+rng.Intn(3))
+		pre := Preprocess(cs)
+		model := make([]bool, cs.NumVars+1)
+		for a := 0; a < 1<<uint(cs.NumVars); a++ {
+			for v := 1; v <= cs.NumVars; v++ {
+				model[v] = a&(1<<uint(v-1)) != 0
+			}
+			if cs.Satisfies(model) != pre.Satisfies(model) {
+				t.Fatalf("Preprocess(%s) returned %s", cs.DIMACS(), pre.DIMACS())
+			}
+		}
+	}
+}
+
+func TestSolvePortfolio(t *testing.T) {
+	rng := rand.New(rand.NewSource(4))
+	for i := 0; i < 50; i++ {
+		cs := randomClauseSet(rng, 20, 70+rng.Intn(30), 3)
+		expected := NewSolverFromClauses(cs).Solve()
+		r, e := SolvePortfolio(context.Background(), cs, DefaultPortfolio(4))
+		if e != nil {
+			t.Fatalf("SolvePortfolio failed: %s", e)
+		}
+		if r.Satisfiable != expected {
+			t.Fatalf("SolvePortfolio returned %v for %s", r.Satisfiable, cs.DIMACS())
+		}
+		if r.Satisfiable && !cs.Satisfies(r.Model) {
+			t.Fatalf("Invalid model for %s", cs.DIMACS())
+		}
+	}
+}
+
+func TestSolvePortfolioCancel(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, e := SolvePortfolio(ctx, pigeonhole(13, 12), DefaultPortfolio(3))
+	if e == nil {
+		t.Fatalf("Pigeonhole formula solved before the deadline")
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Fatalf("Cancellation took %s", time.Since(start))
+	}
+}
@@ -0,0 +1,123 @@
+package logic
+
+import (
+	"sort"
+)
+
+// Preprocess returns a clause set with the same variables and the
+// same models as cs. Unit clauses are propagated and kept as units,
+// satisfied clauses and false literals are removed, as are duplicate
+// literals, tautologies and subsumed clauses.
+func Preprocess(cs *ClauseSet) *ClauseSet {
+	r := &ClauseSet{
+		NumVars: cs.NumVars,
+		Index:   make(map[string]int, len(cs.Index)),
+		Names:   append([]string(nil), cs.Names...),
+	}
+	for name, idx := range cs.Index {
+		r.Index[name] = idx
+	}
+
+	s := NewSolverFromClauses(cs)
+	if !s.ok {
+		r.Clauses = []Clause{Clause{}}
+		return r
+	}
+	for _, l := range s.trail {
+		r.Clauses = append(r.Clauses, Clause{l})
+	}
+
+	clauses := make([]Clause, 0, len(cs.Clauses))
+	for _, c := range cs.Clauses {
+		sorted := append(Clause(nil), c...)
+		sort.Sort(litSlice(sorted))
+		keep := make(Clause, 0, len(sorted))
+		drop := false
+		for i, l := range sorted {
+			if i > 0 && sorted[i-1] == l {
+				continue
+			}
+			if i > 0 && sorted[i-1] == -l || s.value(l) == lTrue {
+				drop = true
+				break
+			}
+			if s.value(l) == lUndef {
+				keep = append(keep, l)
+			}
+		}
+		if !drop {
+			clauses = append(clauses, keep)
+		}
+	}
+	r.Clauses = append(r.Clauses, removeSubsumed(clauses, cs.NumVars)...)
+	return r
+}
+
+type byLength []Clause
+
+func (c byLength) Len() int {
+	return len(c)
+}
+
+func (c byLength) Less(i, j int) bool {
+	return len(c[i]) < len(c[j])
+}
+
+func (c byLength) Swap(i, j int) {
+	c[i], c[j] = c[j], c[i]
+}
+
+// Removes every clause which is a superset of another clause. The
+// literals of every clause have to be sorted and unique.
+func removeSubsumed(clauses []Clause, nvars int) []Clause {
+	sort.Stable(byLength(clauses))
+	occurs := make([][]int, 2*(nvars+1))
+	for i, c := range clauses {
+		for _, l := range c {
+			occurs[l.code()] = append(occurs[l.code()], i)
+		}
+	}
+	subsumed := make([]bool, len(clauses))
+	for i, c := range clauses {
+		if subsumed[i] || len(c) == 0 {
+			continue
+		}
+		// Every clause subsumed by c contains its rarest literal.
+		rarest := c[0]
+		for _, l := range c {
+			if len(occurs[l.code()]) < len(occurs[rarest.code()]) {
+				rarest = l
+			}
+		}
+		for _, j := range occurs[rarest.code()] {
+			if j != i && !subsumed[j] && isSubset(c, clauses[j]) && (len(c) < len(clauses[j]) || i < j) {
+				subsumed[j] = true
+			}
+		}
+	}
+	r := make([]Clause, 0, len(clauses))
+	for i, c := range clauses {
+		if !subsumed[i] {
+			r = append(r, c)
+		}
+	}
+	return r
+}
+
+// Both clauses have to be sorted by litSlice order.
+func isSubset(a, b Clause) bool {
+	if len(a) > len(b) {
+		return false
+	}
+	j := 0
+	for _, l := range a {
+		for j < len(b) && litLess(b[j], l) {
+			j++
+		}
+		if j == len(b) || b[j] != l {
+			return false
+		}
+		j++
+	}
+	return true
+}
@@ -0,0 +1,269 @@
+package logic
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Notation describes how a Printer writes operators and leafs.
+// Operators without a symbol are written as functions, name(a, b).
+type Notation struct {
+	Symbols map[string]string
+	// Name of operators written as functions, the operator's name if
+	// missing.
+	Functions map[string]string
+	Leaf      func(string) string
+	// Written before every line of wrapped output, once per level of
+	// indentation, and between lines.
+	LineStart, Indent, LineBreak string
+}
+
+var (
+	Unicode = &Notation{
+		Symbols: map[string]string{
+			NOT:  "¬",
+			AND:  "∧",
+			OR:   "∨",
+			IF:   "→",
+			IFF:  "↔",
+			XOR:  "⊕",
+			NAND: "↑",
+			NOR:  "↓",
+		},
+		Leaf:      func(name string) string { return name },
+		Indent:    "  ",
+		LineBreak: "\n",
+	}
+	// Wrapped LaTeX output is meant for an align* environment.
+	LaTeX = &Notation{
+		Symbols: map[string]string{
+			NOT:  `\neg `,
+			AND:  `\land`,
+			OR:   `\lor`,
+			IF:   `\rightarrow`,
+			IFF:  `\leftrightarrow`,
+			XOR:  `\oplus`,
+			NAND: `\uparrow`,
+			NOR:  `\downarrow`,
+		},
+		Functions: map[string]string{
+			ITE: `\mathrm{ite}`,
+		},
+		Leaf:      latexLeaf,
+		LineStart: "& ",
+		Indent:    `\quad `,
+		LineBreak: " \\\\\n",
+	}
+	Notations = map[string]*Notation{
+		"unicode": Unicode,
+		"latex":   LaTeX,
+	}
+)
+
+// Binding strength of the infix operators. Operators of the same
+// strength are parenthesized when nested, unless they are the same
+// associative operator.
+var precedence = map[string]int{
+	NOT:  5,
+	AND:  4,
+	NAND: 4,
+	OR:   3,
+	NOR:  3,
+	XOR:  3,
+	IF:   2,
+	IFF:  1,
+}
+
+var associative = map[string]bool{
+	AND: true,
+	OR:  true,
+	XOR: true,
+}
+
+// Writes names like m_3_t=2 as m_{3,t=2}. Only the prefixes m_, r_
+// and a_ and the suffix _t= are subscripts, other underscores belong
+// to the ID.
+func latexLeaf(name string) string {
+	base, sub := name, make([]string, 0, 2)
+	time := ""
+	if i := strings.LastIndex(base, "_t="); i > 0 {
+		base, time = base[:i], base[i+1:]
+	}
+	if len(base) > 2 && base[1] == '_' && strings.ContainsRune("mra", rune(base[0])) {
+		base, sub = base[:1], append(sub, base[2:])
+	}
+	if time != "" {
+		sub = append(sub, time)
+	}
+	base = latexEscape(base)
+	if utf8.RuneCountInString(base) > 1 {
+		base = `\mathit{` + base + "}"
+	}
+	if len(sub) == 0 {
+		return base
+	}
+	for i := range sub {
+		sub[i] = latexEscape(sub[i])
+	}
+	return base + "_{" + strings.Join(sub, ",") + "}"
+}
+
+func latexEscape(s string) string {
+	return strings.NewReplacer(
+		`\`, `\backslash `,
+		"{", `\{`,
+		"}", `\}`,
+		"#", `\#`,
+		"_", `\_`,
+		"$", `\$`,
+		"%", `\%`,
+		"&", `\&`,
+		"^", `\hat{}`,
+		"~", `\sim `,
+	).Replace(s)
+}
+
+// Printer writes formulas in infix notation. Operations which do
+// not fit into Width are broken into one operand per line, indented
+// by their depth. Width 0 never breaks lines.
+type Printer struct {
+	Notation *Notation
+	Width    int
+}
+
+type line struct {
+	depth int
+	text  string
+}
+
+// Format returns n on one line if it fits, else on several lines
+// joined by the line break of the notation.
+func (p *Printer) Format(n Node) string {
+	lines := p.lines(n, "", "", 0, 0)
+	if len(lines) == 1 {
+		return lines[0].text
+	}
+	r := make([]string, len(lines))
+	for i, l := range lines {
+		r[i] = p.Notation.LineStart + strings.Repeat(p.Notation.Indent, l.depth) + l.text
+	}
+	return strings.Join(r, p.Notation.LineBreak)
+}
+
+// Returns whether x has to be parenthesized as an operand of an
+// operation with the given operator and precedence.
+func needsParens(x *Operation, parent string, parentPrec int) bool {
+	prec, infix := precedence[x.Operator]
+	if !infix || len(x.Operands) == 1 || x.Operator == NOT {
+		return false
+	}
+	return prec < parentPrec || prec == parentPrec && !(x.Operator == parent && associative[parent])
+}
+
+// IF and IFF fold from the left like Eval, so a → b → c is written
+// as the nested (a → b) → c.
+func foldLeft(x *Operation) *Operation {
+	if (x.Operator != IF && x.Operator != IFF) || len(x.Operands) <= 2 {
+		return x
+	}
+	last := len(x.Operands) - 1
+	return NewOperation(x.Operator, foldLeft(NewOperation(x.Operator, x.Operands[:last]...)), x.Operands[last])
+}
+
+func (p *Printer) symbol(op string) (string, bool) {
+	s, ok := p.Notation.Symbols[op]
+	return s, ok
+}
+
+func (p *Printer) function(op string) string {
+	if name, ok := p.Notation.Functions[op]; ok {
+		return name
+	}
+	if o, ok := operators[op]; ok {
+		return o.Name
+	}
+	return op
+}
+
+// Writes n on one line.
+func (p *Printer) inline(n Node, parent string, parentPrec int) string {
+	x, ok := n.(*Operation)
+	if !ok {
+		if n == nil {
+			return "<nil>"
+		}
+		return p.Notation.Leaf(string(n.(Leaf)))
+	}
+	x = foldLeft(x)
+	operands := make([]string, len(x.Operands))
+	sym, infix := p.symbol(x.Operator)
+	if !infix || x.Operator == NOT && len(x.Operands) != 1 {
+		for i, operand := range x.Operands {
+			operands[i] = p.inline(operand, "", 0)
+		}
+		return p.function(x.Operator) + "(" + strings.Join(operands, ", ") + ")"
+	}
+	prec := precedence[x.Operator]
+	for i, operand := range x.Operands {
+		operands[i] = p.inline(operand, x.Operator, prec)
+	}
+	var s string
+	if x.Operator == NOT {
+		s = sym + operands[0]
+	} else {
+		s = strings.Join(operands, " "+sym+" ")
+	}
+	if needsParens(x, parent, parentPrec) {
+		s = "(" + s + ")"
+	}
+	return s
+}
+
+// Writes n as an operand of parent, starting at the given depth
+// with prefix before its first line, breaking it if it does not fit.
+func (p *Printer) lines(n Node, prefix string, parent string, depth int, parentPrec int) []line {
+	s := prefix + p.inline(n, parent, parentPrec)
+	width := utf8.RuneCountInString(p.Notation.LineStart) +
+		depth*utf8.RuneCountInString(p.Notation.Indent) +
+		utf8.RuneCountInString(s)
+	x, ok := n.(*Operation)
+	if p.Width == 0 || width <= p.Width || !ok || len(x.Operands) == 0 {
+		return []line{{depth, s}}
+	}
+	x = foldLeft(x)
+
+	sym, infix := p.symbol(x.Operator)
+	if x.Operator == NOT && len(x.Operands) == 1 {
+		return p.lines(x.Operands[0], prefix+sym, NOT, depth, precedence[NOT])
+	}
+	if !infix || x.Operator == NOT {
+		r := []line{{depth, prefix + p.function(x.Operator) + "("}}
+		for i, operand := range x.Operands {
+			sub := p.lines(operand, "", "", depth+1, 0)
+			if i < len(x.Operands)-1 {
+				sub[len(sub)-1].text += ","
+			}
+			r = append(r, sub...)
+		}
+		return append(r, line{depth, ")"})
+	}
+
+	r := make([]line, 0)
+	inner := depth
+	parens := needsParens(x, parent, parentPrec)
+	if parens {
+		r = append(r, line{depth, prefix + "("})
+		inner++
+		prefix = ""
+	}
+	for i, operand := range x.Operands {
+		if i > 0 {
+			prefix = sym + " "
+		}
+		r = append(r, p.lines(operand, prefix, x.Operator, inner, precedence[x.Operator])...)
+	}
+	if parens {
+		r = append(r, line{depth, ")"})
+	}
+	return r
+}
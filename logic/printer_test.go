@@ -0,0 +1,80 @@
+package logic
+
+import (
+	"testing"
+)
+
+func TestUnicode(t *testing.T) {
+	a, b, c := NewLeaf("a"), NewLeaf("b"), NewLeaf("c")
+	p := &Printer{Notation: Unicode}
+	for _, x := range []struct {
+		n Node
+		s string
+	}{
+		{NewOperation(AND, a, NewOperation(OR, b, c)), "a ∧ (b ∨ c)"},
+		{NewOperation(OR, NewOperation(AND, a, b), c), "a ∧ b ∨ c"},
+		{NewOperation(AND, a, NewOperation(AND, b, c)), "a ∧ b ∧ c"},
+		{NewOperation(IF, NewOperation(IF, a, b), c), "(a → b) → c"},
+		{NewOperation(IF, a, b, c), "(a → b) → c"},
+		{NewOperation(IFF, a, b, NewOperation(IFF, b, c), c), "((a ↔ b) ↔ (b ↔ c)) ↔ c"},
+		{NewOperation(NOT, NewOperation(IFF, a, b)), "¬(a ↔ b)"},
+		{NewOperation(NAND, a, NewOperation(NAND, b, c)), "a ↑ (b ↑ c)"},
+		{NewOperation(ITE, a, NewOperation(NOT, b), c), "ite(a, ¬b, c)"},
+	} {
+		if s := p.Format(x.n); s != x.s {
+			t.Fatalf("Format(%s) = %q, expected %q", x.n, s, x.s)
+		}
+	}
+}
+
+func TestLaTeX(t *testing.T) {
+	n := NewOperation(IF, NewLeaf("m_3_t=2"), NewOperation(NOT, NewLeaf("r_10_t=1")))
+	p := &Printer{Notation: LaTeX}
+	expected := `m_{3,t=2} \rightarrow \neg r_{10,t=1}`
+	if s := p.Format(n); s != expected {
+		t.Fatalf("Format(%s) = %q", n, s)
+	}
+	for name, expected := range map[string]string{
+		"ab_c%":            `\mathit{ab\_c\%}`,
+		"m_M_glc__D_e_t=1": `m_{M\_glc\_\_D\_e,t=1}`,
+		"a_R_PGI":          `a_{R\_PGI}`,
+		"x_t=0":            `x_{t=0}`,
+	} {
+		if s := latexLeaf(name); s != expected {
+			t.Fatalf("latexLeaf(%q) = %q, expected %q", name, s, expected)
+		}
+	}
+}
+
+func TestWrap(t *testing.T) {
+	a, b, c, d := NewLeaf("a"), NewLeaf("b"), NewLeaf("c"), NewLeaf("d")
+	n := NewOperation(AND, NewOperation(OR, a, b, c), d, NewOperation(NOT, NewOperation(OR, c, d)))
+	p := &Printer{Notation: Unicode, Width: 11}
+	expected := "(a ∨ b ∨ c)\n" +
+		"∧ d\n" +
+		"∧ ¬(c ∨ d)"
+	if s := p.Format(n); s != expected {
+		t.Fatalf("Format(%s) =\n%s", n, s)
+	}
+	p = &Printer{Notation: Unicode, Width: 8}
+	expected = "(\n" +
+		"  a\n" +
+		"  ∨ b\n" +
+		"  ∨ c\n" +
+		")\n" +
+		"∧ d\n" +
+		"∧ ¬(\n" +
+		"  c\n" +
+		"  ∨ d\n" +
+		")"
+	if s := p.Format(n); s != expected {
+		t.Fatalf("Format(%s) =\n%s", n, s)
+	}
+	p = &Printer{Notation: LaTeX, Width: 30}
+	expected = `& (a \lor b \lor c) \\` + "\n" +
+		`& \land d \\` + "\n" +
+		`& \land \neg (c \lor d)`
+	if s := p.Format(n); s != expected {
+		t.Fatalf("Format(%s) =\n%s", n, s)
+	}
+}
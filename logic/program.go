@@ -0,0 +1,272 @@
+package logic
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+)
+
+type opcode uint8
+
+const (
+	opNot opcode = iota
+	opAnd
+	opOr
+	opIf
+	opIff
+)
+
+var opcodes = map[string]opcode{
+	NOT: opNot,
+	AND: opAnd,
+	OR:  opOr,
+	IF:  opIf,
+	IFF: opIff,
+}
+
+// Operands of an instruction are args[start:end].
+type instr struct {
+	op         opcode
+	dst        int32
+	start, end int32
+}
+
+// Program is a formula compiled to a flat list of instructions over
+// registers. Registers 0 to len(Vars)-1 hold the variables and every
+// instruction writes one further register. Shared subformulas are
+// computed once. Evaluation works on 64 assignments at once, one per bit.
+// A Program is not safe for concurrent use.
+type Program struct {
+	// Leaf names in the order of their registers
+	Vars  []string
+	Index map[string]int
+	code  []instr
+	args  []int32
+	regs  []uint64
+	out   int32
+}
+
+type programCompiler struct {
+	p *Program
+	// Registers of already compiled shared subformulas
+	done map[*Operation]int32
+}
+
+// Compile translates n into a Program. The numbers of operands are
+// checked as in Eval, but for the whole formula up front. Operators
+// without an instruction of their own are compiled rewritten.
+func Compile(n Node) (*Program, error) {
+	c := &programCompiler{
+		p:    &Program{Index: make(map[string]int)},
+		done: make(map[*Operation]int32),
+	}
+	for name := range DefaultMap(n) {
+		c.p.Vars = append(c.p.Vars, name)
+	}
+	sort.Strings(c.p.Vars)
+	for i, name := range c.p.Vars {
+		c.p.Index[name] = i
+	}
+	out, e := c.compile(n)
+	if e != nil {
+		return nil, e
+	}
+	c.p.out = out
+	c.p.regs = make([]uint64, len(c.p.Vars)+len(c.p.code))
+	return c.p, nil
+}
+
+func (c *programCompiler) compile(n Node) (int32, error) {
+	switch x := n.(type) {
+	case Leaf:
+		return int32(c.p.Index[string(x)]), nil
+	case *Operation:
+		if r, ok := c.done[x]; ok {
+			return r, nil
+		}
+		operator, e := operatorOf(x)
+		if e != nil {
+			return 0, e
+		}
+		op, ok := opcodes[x.Operator]
+		if !ok {
+			// The rewrite shares the operands, so they are still
+			// compiled only once.
+			r, e := c.compile(operator.Rewrite(x.Operands))
+			if e != nil {
+				return 0, e
+			}
+			c.done[x] = r
+			return r, nil
+		}
+		operands := make([]int32, len(x.Operands))
+		for i, operand := range x.Operands {
+			r, e := c.compile(operand)
+			if e != nil {
+				return 0, e
+			}
+			operands[i] = r
+		}
+		start := int32(len(c.p.args))
+		c.p.args = append(c.p.args, operands...)
+		dst := int32(len(c.p.Vars) + len(c.p.code))
+		c.p.code = append(c.p.code, instr{op, dst, start, int32(len(c.p.args))})
+		c.done[x] = dst
+		return dst, nil
+	}
+	return 0, fmt.Errorf("Cannot compile %v", n)
+}
+
+// Eval64 evaluates the program on 64 assignments. Bit i of inputs[v]
+// is the value of Vars[v] in assignment i, bit i of the result the
+// value of the formula.
+func (p *Program) Eval64(inputs []uint64) uint64 {
+	regs := p.regs
+	copy(regs, inputs)
+	for _, in := range p.code {
+		args := p.args[in.start:in.end]
+		var r uint64
+		switch in.op {
+		case opNot:
+			r = ^regs[args[0]]
+		case opAnd:
+			r = ^uint64(0)
+			for _, a := range args {
+				r &= regs[a]
+			}
+		case opOr:
+			for _, a := range args {
+				r |= regs[a]
+			}
+		case opIf:
+			// Left fold as in Eval: r = !r || x
+			r = ^uint64(0)
+			for _, a := range args {
+				r = ^r | regs[a]
+			}
+		case opIff:
+			r = ^uint64(0)
+			for _, a := range args {
+				r = ^(r ^ regs[a])
+			}
+		}
+		regs[in.dst] = r
+	}
+	return regs[p.out]
+}
+
+// Eval evaluates the program on a single assignment.
+func (p *Program) Eval(config Configuration) bool {
+	inputs := make([]uint64, len(p.Vars))
+	for i, name := range p.Vars {
+		if config[name] {
+			inputs[i] = 1
+		}
+	}
+	return p.Eval64(inputs)&1 == 1
+}
+
+// TruthTable returns the value of the formula for every assignment,
+// bit i of the table for the assignment where Vars[v] is bit v of i.
+// It panics for more than 30 variables.
+func (p *Program) TruthTable() []uint64 {
+	n := uint(len(p.Vars))
+	if n > 30 {
+		panic("Too many variables for a truth table")
+	}
+	words := 1
+	if n > 6 {
+		words = 1 << (n - 6)
+	}
+	table := make([]uint64, words)
+	inputs := make([]uint64, n)
+	// The lowest six variables alternate within a word.
+	patterns := []uint64{
+		0xaaaaaaaaaaaaaaaa, 0xcccccccccccccccc, 0xf0f0f0f0f0f0f0f0,
+		0xff00ff00ff00ff00, 0xffff0000ffff0000, 0xffffffff00000000,
+	}
+	for w := range table {
+		for v := uint(0); v < n; v++ {
+			if v < 6 {
+				inputs[v] = patterns[v]
+			} else if w&(1<<(v-6)) != 0 {
+				inputs[v] = ^uint64(0)
+			} else {
+				inputs[v] = 0
+			}
+		}
+		table[w] = p.Eval64(inputs)
+	}
+	if n < 6 {
+		table[0] &= 1<<(1<<n) - 1
+	}
+	return table
+}
+
+// Simulate evaluates p and q on rounds times 64 random assignments
+// of the union of their variables and returns an assignment on which
+// they differ, or nil if there is none among them.
+func Simulate(p, q *Program, rounds int, seed int64) Configuration {
+	rng := rand.New(rand.NewSource(seed))
+	names := make(map[string]uint64)
+	for _, name := range append(append([]string(nil), p.Vars...), q.Vars...) {
+		names[name] = 0
+	}
+	pin, qin := make([]uint64, len(p.Vars)), make([]uint64, len(q.Vars))
+	for round := 0; round < rounds; round++ {
+		for name := range names {
+			names[name] = rng.Uint64()
+		}
+		for i, name := range p.Vars {
+			pin[i] = names[name]
+		}
+		for i, name := range q.Vars {
+			qin[i] = names[name]
+		}
+		diff := p.Eval64(pin) ^ q.Eval64(qin)
+		if diff == 0 {
+			continue
+		}
+		bit := uint(0)
+		for diff&(1<<bit) == 0 {
+			bit++
+		}
+		r := make(Configuration)
+		for name, lanes := range names {
+			r[name] = lanes&(1<<bit) != 0
+		}
+		return r
+	}
+	return nil
+}
+
+// Filter evaluates the program on models as returned by the solvers,
+// where index maps leaf names to variable numbers (as the table of
+// FormatSAT). Leafs missing from index or beyond a model are false.
+func (p *Program) Filter(models [][]bool, index map[string]int) []bool {
+	r := make([]bool, len(models))
+	inputs := make([]uint64, len(p.Vars))
+	for base := 0; base < len(models); base += 64 {
+		end := base + 64
+		if end > len(models) {
+			end = len(models)
+		}
+		for i, name := range p.Vars {
+			inputs[i] = 0
+			v, ok := index[name]
+			if !ok {
+				continue
+			}
+			for k := base; k < end; k++ {
+				if v < len(models[k]) && models[k][v] {
+					inputs[i] |= 1 << uint(k-base)
+				}
+			}
+		}
+		out := p.Eval64(inputs)
+		for k := base; k < end; k++ {
+			r[k] = out&(1<<uint(k-base)) != 0
+		}
+	}
+	return r
+}
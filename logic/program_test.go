@@ -0,0 +1,90 @@
+package logic
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func configuration(vars []string, i int) Configuration {
+	c := make(Configuration)
+	for v, name := range vars {
+		c[name] = i&(1<<uint(v)) != 0
+	}
+	return c
+}
+
+func TestProgram(t *testing.T) {
+	g := NewGenerator(1, GeneratorOptions{Vars: 8, Depth: 5})
+	for i := 0; i < 300; i++ {
+		n := g.Formula()
+		p, e := Compile(n)
+		if e != nil {
+			t.Fatal(e)
+		}
+		table := p.TruthTable()
+		for a := 0; a < 1<<uint(len(p.Vars)); a++ {
+			c := configuration(p.Vars, a)
+			expected := n.Eval(c)
+			if p.Eval(c) != expected {
+				t.Fatalf("Program of %s returned %v for %v", n, !expected, c)
+			}
+			if (table[a/64]&(1<<uint(a%64)) != 0) != expected {
+				t.Fatalf("Truth table of %s is wrong for %v", n, c)
+			}
+		}
+	}
+}
+
+func TestProgramErrors(t *testing.T) {
+	for _, n := range []Node{
+		NewOperation(AND),
+		NewOperation(NOT, NewLeaf("a"), NewLeaf("b")),
+		NewOperation("?", NewLeaf("a")),
+		NewOperation(OR, NewLeaf("a"), nil),
+	} {
+		if _, e := Compile(n); e == nil {
+			t.Fatalf("Compile(%s) succeeded", n)
+		}
+	}
+}
+
+func TestSimulate(t *testing.T) {
+	a, b, c := NewLeaf("a"), NewLeaf("b"), NewLeaf("c")
+	p, _ := Compile(NewOperation(NOT, NewOperation(AND, a, b)))
+	q, _ := Compile(NewOperation(OR, NewOperation(NOT, a), NewOperation(NOT, b)))
+	if r := Simulate(p, q, 10, 1); r != nil {
+		t.Fatalf("De Morgan not equivalent on %v", r)
+	}
+	q, _ = Compile(NewOperation(OR, NewOperation(NOT, a), NewOperation(NOT, b), c))
+	r := Simulate(p, q, 10, 1)
+	if r == nil || p.Eval(r) == q.Eval(r) {
+		t.Fatalf("Simulate returned %v", r)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	n := NewGenerator(2, GeneratorOptions{Depth: 6}).Formula()
+	p, _ := Compile(n)
+	index := make(map[string]int)
+	for i := 0; i < 5; i++ {
+		index[VarName(i)] = i + 1
+	}
+	models := make([][]bool, 200)
+	for k := range models {
+		models[k] = make([]bool, 6)
+		for v := range models[k] {
+			models[k][v] = rng.Intn(2) == 0
+		}
+	}
+	r := p.Filter(models, index)
+	for k, m := range models {
+		c := make(Configuration)
+		for name, v := range index {
+			c[name] = m[v]
+		}
+		if r[k] != n.Eval(c) {
+			t.Fatalf("Filter returned %v for model %d", r[k], k)
+		}
+	}
+}
@@ -0,0 +1,339 @@
+package logic
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Version of the JSON envelope and the binary format.
+const FormatVersion = 1
+
+const binaryMagic = "LGCB"
+
+// Tags of the binary prefix encoding.
+const (
+	tagNil = iota
+	tagLeaf
+	tagOperation
+)
+
+type jsonEnvelope struct {
+	Version int             `json:"version"`
+	Formula json.RawMessage `json:"formula"`
+}
+
+type jsonNode struct {
+	Leaf     *string           `json:"leaf,omitempty"`
+	Op       string            `json:"op,omitempty"`
+	Operands []json.RawMessage `json:"operands"`
+}
+
+func (l Leaf) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Leaf string `json:"leaf"`
+	}{string(l)})
+}
+
+// Operands are omitted if the slice is nil and an empty list if it
+// is empty, so that both survive a round trip.
+func (o *Operation) MarshalJSON() ([]byte, error) {
+	operands := make([]json.RawMessage, len(o.Operands))
+	for i, operand := range o.Operands {
+		b, e := marshalNode(operand)
+		if e != nil {
+			return nil, e
+		}
+		operands[i] = b
+	}
+	if o.Operands == nil {
+		return json.Marshal(struct {
+			Op string `json:"op"`
+		}{o.Operator})
+	}
+	return json.Marshal(jsonNode{Op: o.Operator, Operands: operands})
+}
+
+func (o *Operation) UnmarshalJSON(b []byte) error {
+	n, e := unmarshalNode(b)
+	if e != nil {
+		return e
+	}
+	op, ok := n.(*Operation)
+	if !ok {
+		return errors.New("Not an operation")
+	}
+	*o = *op
+	return nil
+}
+
+func marshalNode(n Node) ([]byte, error) {
+	if n == nil {
+		return []byte("null"), nil
+	}
+	return json.Marshal(n)
+}
+
+func unmarshalNode(b []byte) (Node, error) {
+	if string(b) == "null" {
+		return nil, nil
+	}
+	var j jsonNode
+	if e := json.Unmarshal(b, &j); e != nil {
+		return nil, e
+	}
+	if j.Leaf != nil {
+		if j.Op != "" || j.Operands != nil {
+			return nil, errors.New("Node is both leaf and operation")
+		}
+		return Leaf(*j.Leaf), nil
+	}
+	if _, ok := operators[j.Op]; !ok {
+		return nil, fmt.Errorf("Unknown operator %q", j.Op)
+	}
+	o := &Operation{Operator: j.Op}
+	if j.Operands != nil {
+		o.Operands = make([]Node, len(j.Operands))
+	}
+	for i, operand := range j.Operands {
+		n, e := unmarshalNode(operand)
+		if e != nil {
+			return nil, e
+		}
+		o.Operands[i] = n
+	}
+	return o, nil
+}
+
+// EncodeJSON writes n wrapped in an envelope carrying the format
+// version.
+func EncodeJSON(w io.Writer, n Node) error {
+	b, e := marshalNode(n)
+	if e != nil {
+		return e
+	}
+	return json.NewEncoder(w).Encode(jsonEnvelope{FormatVersion, b})
+}
+
+// DecodeJSON reads a formula written by EncodeJSON.
+func DecodeJSON(r io.Reader) (Node, error) {
+	var env jsonEnvelope
+	if e := json.NewDecoder(r).Decode(&env); e != nil {
+		return nil, e
+	}
+	if env.Version != FormatVersion {
+		return nil, fmt.Errorf("Unsupported format version %d", env.Version)
+	}
+	if env.Formula == nil {
+		return nil, errors.New("Envelope has no formula")
+	}
+	return unmarshalNode(env.Formula)
+}
+
+// EncodeBinary writes n in a compact binary format: the magic
+// "LGCB", a version byte, the tables of leaf names and operators and
+// the tree in prefix order. Every node is a tag byte followed by a
+// table index (leafs) or an operator index and the number of
+// operands plus one, zero meaning a nil operand slice. All numbers
+// are unsigned varints, names are prefixed by their length.
+func EncodeBinary(w io.Writer, n Node) error {
+	leafs, ops := make(map[string]int), make(map[string]int)
+	leafNames, opNames := make([]string, 0), make([]string, 0)
+	var collect func(Node)
+	collect = func(n Node) {
+		switch x := n.(type) {
+		case Leaf:
+			if _, ok := leafs[string(x)]; !ok {
+				leafs[string(x)] = len(leafNames)
+				leafNames = append(leafNames, string(x))
+			}
+		case *Operation:
+			if _, ok := ops[x.Operator]; !ok {
+				ops[x.Operator] = len(opNames)
+				opNames = append(opNames, x.Operator)
+			}
+			for _, operand := range x.Operands {
+				collect(operand)
+			}
+		}
+	}
+	collect(n)
+
+	b := bufio.NewWriter(w)
+	buf := make([]byte, binary.MaxVarintLen64)
+	uvarint := func(x int) {
+		b.Write(buf[:binary.PutUvarint(buf, uint64(x))])
+	}
+	b.WriteString(binaryMagic)
+	b.WriteByte(FormatVersion)
+	for _, table := range [][]string{leafNames, opNames} {
+		uvarint(len(table))
+		for _, name := range table {
+			uvarint(len(name))
+			b.WriteString(name)
+		}
+	}
+	var write func(Node)
+	write = func(n Node) {
+		switch x := n.(type) {
+		case nil:
+			b.WriteByte(tagNil)
+		case Leaf:
+			b.WriteByte(tagLeaf)
+			uvarint(leafs[string(x)])
+		case *Operation:
+			b.WriteByte(tagOperation)
+			uvarint(ops[x.Operator])
+			if x.Operands == nil {
+				uvarint(0)
+			} else {
+				uvarint(len(x.Operands) + 1)
+			}
+			for _, operand := range x.Operands {
+				write(operand)
+			}
+		}
+	}
+	write(n)
+	return b.Flush()
+}
+
+// DecodeBinary reads a formula written by EncodeBinary.
+func DecodeBinary(r io.Reader) (Node, error) {
+	b := bufio.NewReader(r)
+	header := make([]byte, len(binaryMagic)+1)
+	if _, e := io.ReadFull(b, header); e != nil {
+		return nil, e
+	}
+	if string(header[:len(binaryMagic)]) != binaryMagic {
+		return nil, errors.New("Not a binary formula")
+	}
+	if header[len(binaryMagic)] != FormatVersion {
+		return nil, fmt.Errorf("Unsupported format version %d", header[len(binaryMagic)])
+	}
+	number := func() (int, error) {
+		x, e := binary.ReadUvarint(b)
+		if e == nil && x > 1<<31 {
+			e = errors.New("Number out of range")
+		}
+		return int(x), e
+	}
+	tables := make([][]string, 2)
+	for t := range tables {
+		n, e := number()
+		if e != nil {
+			return nil, e
+		}
+		for i := 0; i < n; i++ {
+			l, e := number()
+			if e != nil {
+				return nil, e
+			}
+			// Copied instead of preallocated, so that a corrupt
+			// length cannot allocate more than the file holds
+			var name bytes.Buffer
+			if _, e := io.CopyN(&name, b, int64(l)); e != nil {
+				return nil, e
+			}
+			tables[t] = append(tables[t], name.String())
+		}
+	}
+	leafNames, opNames := tables[0], tables[1]
+	for _, op := range opNames {
+		if _, ok := operators[op]; !ok {
+			return nil, fmt.Errorf("Unknown operator %q", op)
+		}
+	}
+
+	var read func() (Node, error)
+	read = func() (Node, error) {
+		tag, e := b.ReadByte()
+		if e != nil {
+			return nil, e
+		}
+		switch tag {
+		case tagNil:
+			return nil, nil
+		case tagLeaf:
+			i, e := number()
+			if e != nil {
+				return nil, e
+			}
+			if i >= len(leafNames) {
+				return nil, fmt.Errorf("Leaf index %d out of range", i)
+			}
+			return Leaf(leafNames[i]), nil
+		case tagOperation:
+			i, e := number()
+			if e != nil {
+				return nil, e
+			}
+			if i >= len(opNames) {
+				return nil, fmt.Errorf("Operator index %d out of range", i)
+			}
+			n, e := number()
+			if e != nil {
+				return nil, e
+			}
+			o := &Operation{Operator: opNames[i]}
+			if n > 0 {
+				o.Operands = make([]Node, 0)
+			}
+			for j := 0; j < n-1; j++ {
+				operand, e := read()
+				if e != nil {
+					return nil, e
+				}
+				o.Operands = append(o.Operands, operand)
+			}
+			return o, nil
+		}
+		return nil, fmt.Errorf("Invalid tag %d", tag)
+	}
+	n, e := read()
+	if e == io.EOF {
+		e = io.ErrUnexpectedEOF
+	}
+	return n, e
+}
+
+// SaveFormula writes n to a file, as JSON if the name ends in .json
+// and in the binary format otherwise.
+func SaveFormula(name string, n Node) error {
+	f, e := os.Create(name)
+	if e != nil {
+		return e
+	}
+	if strings.HasSuffix(name, ".json") {
+		e = EncodeJSON(f, n)
+	} else {
+		e = EncodeBinary(f, n)
+	}
+	if e != nil {
+		f.Close()
+		return e
+	}
+	return f.Close()
+}
+
+// LoadFormula reads a file written by SaveFormula. The format is
+// recognized by the magic of the binary format.
+func LoadFormula(name string) (Node, error) {
+	f, e := os.Open(name)
+	if e != nil {
+		return nil, e
+	}
+	defer f.Close()
+	b := bufio.NewReader(f)
+	magic, _ := b.Peek(len(binaryMagic))
+	if string(magic) == binaryMagic {
+		return DecodeBinary(b)
+	}
+	return DecodeJSON(b)
+}
@@ -0,0 +1,91 @@
+package logic
+
+import (
+	"bytes"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func randomTree(rng *rand.Rand, depth int) Node {
+	if depth == 0 || rng.Intn(4) == 0 {
+		switch rng.Intn(10) {
+		case 0:
+			return nil
+		case 1:
+			return Leaf("")
+		}
+		return Leaf(string(rune('a' + rng.Intn(26))))
+	}
+	ops := []string{NOT, AND, OR, IF, IFF}
+	o := NewOperation(ops[rng.Intn(len(ops))])
+	switch rng.Intn(6) {
+	case 0:
+	case 1:
+		o.Operands = []Node{}
+	default:
+		for i := rng.Intn(4); i >= 0; i-- {
+			o.PushOperands(randomTree(rng, depth-1))
+		}
+	}
+	return o
+}
+
+func TestSerializeRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		n := randomTree(rng, 5)
+
+		var b bytes.Buffer
+		if e := EncodeJSON(&b, n); e != nil {
+			t.Fatal(e)
+		}
+		r, e := DecodeJSON(&b)
+		if e != nil {
+			t.Fatal(e)
+		}
+		if !reflect.DeepEqual(n, r) {
+			t.Fatalf("JSON round trip changed %v to %v", n, r)
+		}
+
+		b.Reset()
+		if e := EncodeBinary(&b, n); e != nil {
+			t.Fatal(e)
+		}
+		r, e = DecodeBinary(&b)
+		if e != nil {
+			t.Fatal(e)
+		}
+		if !reflect.DeepEqual(n, r) {
+			t.Fatalf("Binary round trip changed %v to %v", n, r)
+		}
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	for _, s := range []string{
+		`{"version":2,"formula":{"leaf":"a"}}`,
+		`{"version":1}`,
+		`{"version":1,"formula":{"op":"?","operands":[]}}`,
+		`{"version":1,"formula":{"leaf":"a","op":"^"}}`,
+	} {
+		if _, e := DecodeJSON(strings.NewReader(s)); e == nil {
+			t.Fatalf("DecodeJSON accepted %s", s)
+		}
+	}
+
+	var b bytes.Buffer
+	EncodeBinary(&b, NewOperation(AND, NewLeaf("a"), NewLeaf("b")))
+	data := b.Bytes()
+	for i := 0; i < len(data); i++ {
+		if _, e := DecodeBinary(bytes.NewReader(data[:i])); e == nil {
+			t.Fatalf("DecodeBinary accepted %d of %d bytes", i, len(data))
+		}
+	}
+	// One leaf whose name claims 2^31 bytes
+	huge := append([]byte(binaryMagic), FormatVersion, 1, 0x80, 0x80, 0x80, 0x80, 0x08, 'a')
+	if _, e := DecodeBinary(bytes.NewReader(huge)); e == nil {
+		t.Fatal("DecodeBinary accepted a truncated name")
+	}
+}
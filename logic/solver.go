@@ -0,0 +1,804 @@
+package logic
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"sort"
+	"strconv"
+)
+
+const (
+	lUndef int8 = 0
+	lTrue  int8 = 1
+	lFalse int8 = -1
+)
+
+type RestartPolicy int
+
+const (
+	LubyRestarts RestartPolicy = iota
+	GeometricRestarts
+	NoRestarts
+)
+
+// Phase is the value assigned to a variable on its first decision.
+// Later decisions reuse the last value (phase saving).
+type Phase int
+
+const (
+	PhaseFalse Phase = iota
+	PhaseTrue
+	PhaseRandom
+)
+
+type SolverOptions struct {
+	Seed     int64
+	Restarts RestartPolicy
+	Phase    Phase
+	// Fraction of decisions made on a random variable.
+	RandomFreq float64
+	// Simplify the clause set with Preprocess before solving.
+	// Only used by SolvePortfolio.
+	Preprocess bool
+	// If set, the portfolio member runs LocalSearch instead of the
+	// CDCL solver. It can only contribute models.
+	LocalSearch *LocalSearchOptions
+}
+
+type clause struct {
+	lits     []Lit
+	learnt   bool
+	activity float64
+	lbd      int
+	deleted  bool
+}
+
+// Solver is a CDCL SAT solver (two watched literals, VSIDS,
+// phase saving, Luby restarts) on variables in DIMACS numbering.
+// It is incremental: clauses can be added between calls to Solve
+// and every call can be given a set of assumptions.
+type Solver struct {
+	ok       bool
+	nvars    int
+	clauses  []*clause
+	learnts  []*clause
+	watches  [][]*clause
+	assigns  []int8
+	level    []int
+	reason   []*clause
+	phase    []bool
+	seen     []bool
+	activity []float64
+	order    varHeap
+	trail    []Lit
+	trailLim []int
+	qhead    int
+
+	varInc      float64
+	claInc      float64
+	maxLearnts  float64
+	model       []bool
+	assumptions []Lit
+	// Set if the last search failed because of an assumption.
+	failedAssumption bool
+	opts             SolverOptions
+	rng              *rand.Rand
+	ctx              context.Context
+
+	Conflicts    int
+	Decisions    int
+	Propagations int
+
+	// If Proof is set, every learnt and deleted clause is written to
+	// it in DRAT format, so every unsatisfiability result found
+	// without assumptions comes with a proof ending in the empty
+	// clause. Clauses added after the first call to Solve are not
+	// part of the original formula and invalidate the proof.
+	Proof io.Writer
+
+	// If set, Export is called with every learnt clause and Import
+	// is called on every restart to obtain clauses learnt elsewhere.
+	// Imported clauses must be implied by the formula.
+	Export func([]Lit)
+	Import func() [][]Lit
+}
+
+func NewSolver(nvars int) *Solver {
+	return NewSolverWithOptions(nvars, SolverOptions{})
+}
+
+func NewSolverWithOptions(nvars int, opts SolverOptions) *Solver {
+	s := &Solver{
+		ok:     true,
+		varInc: 1,
+		claInc: 1,
+		opts:   opts,
+		rng:    rand.New(rand.NewSource(opts.Seed)),
+	}
+	s.order.activity = &s.activity
+	s.watches = make([][]*clause, 2)
+	s.assigns = make([]int8, 1)
+	s.level = make([]int, 1)
+	s.reason = make([]*clause, 1)
+	s.phase = make([]bool, 1)
+	s.seen = make([]bool, 1)
+	s.activity = make([]float64, 1)
+	s.order.index = make([]int, 1)
+	s.ensureVars(nvars)
+	return s
+}
+
+// NewSolverFromClauses creates a solver containing every clause of cs.
+func NewSolverFromClauses(cs *ClauseSet) *Solver {
+	s := NewSolver(cs.NumVars)
+	for _, c := range cs.Clauses {
+		s.AddClause(c...)
+	}
+	return s
+}
+
+func (s *Solver) NumVars() int {
+	return s.nvars
+}
+
+func (s *Solver) ensureVars(n int) {
+	for s.nvars < n {
+		s.nvars++
+		s.watches = append(s.watches, nil, nil)
+		s.assigns = append(s.assigns, lUndef)
+		s.level = append(s.level, 0)
+		s.reason = append(s.reason, nil)
+		s.phase = append(s.phase, s.opts.Phase == PhaseTrue ||
+			(s.opts.Phase == PhaseRandom && s.rng.Intn(2) == 1))
+		s.seen = append(s.seen, false)
+		s.activity = append(s.activity, 0)
+		s.order.index = append(s.order.index, -1)
+		s.order.insert(s.nvars)
+	}
+}
+
+func (s *Solver) value(l Lit) int8 {
+	v := s.assigns[l.Var()]
+	if l < 0 {
+		return -v
+	}
+	return v
+}
+
+func (s *Solver) decisionLevel() int {
+	return len(s.trailLim)
+}
+
+// AddClause adds a clause to the solver. It returns false if the
+// solver is known to be unsatisfiable afterwards.
+// Must not be called while Solve is running.
+func (s *Solver) AddClause(lits ...Lit) bool {
+	if !s.ok {
+		return false
+	}
+	s.cancelUntil(0)
+	c := make([]Lit, 0, len(lits))
+	shortened := false
+	for _, l := range lits {
+		if l.Var() > s.nvars {
+			s.ensureVars(l.Var())
+		}
+	}
+	sorted := append([]Lit(nil), lits...)
+	sort.Sort(litSlice(sorted))
+	for i, l := range sorted {
+		if i > 0 && sorted[i-1] == l {
+			continue
+		}
+		if i > 0 && sorted[i-1] == -l {
+			// Tautology
+			return true
+		}
+		switch s.value(l) {
+		case lTrue:
+			return true
+		case lUndef:
+			c = append(c, l)
+		case lFalse:
+			shortened = true
+		}
+	}
+	if shortened {
+		s.trace("", c)
+	}
+
+	switch len(c) {
+	case 0:
+		s.ok = false
+		return false
+	case 1:
+		s.enqueue(c[0], nil)
+		if s.propagate() != nil {
+			s.trace("", nil)
+			s.ok = false
+			return false
+		}
+		return true
+	}
+	cl := &clause{lits: c}
+	s.clauses = append(s.clauses, cl)
+	s.attach(cl)
+	return true
+}
+
+func (s *Solver) attach(c *clause) {
+	s.watches[c.lits[0].code()] = append(s.watches[c.lits[0].code()], c)
+	s.watches[c.lits[1].code()] = append(s.watches[c.lits[1].code()], c)
+}
+
+func (s *Solver) enqueue(l Lit, from *clause) {
+	v := l.Var()
+	if l > 0 {
+		s.assigns[v] = lTrue
+	} else {
+		s.assigns[v] = lFalse
+	}
+	s.level[v] = s.decisionLevel()
+	s.reason[v] = from
+	s.trail = append(s.trail, l)
+}
+
+// Returns the conflicting clause or nil.
+func (s *Solver) propagate() *clause {
+	for s.qhead < len(s.trail) {
+		p := s.trail[s.qhead]
+		s.qhead++
+		s.Propagations++
+		falseLit := -p
+		ws := s.watches[falseLit.code()]
+		i, j := 0, 0
+		for i < len(ws) {
+			c := ws[i]
+			i++
+			if c.deleted {
+				continue
+			}
+			if c.lits[0] == falseLit {
+				c.lits[0], c.lits[1] = c.lits[1], c.lits[0]
+			}
+			if s.value(c.lits[0]) == lTrue {
+				ws[j] = c
+				j++
+				continue
+			}
+			found := false
+			for k := 2; k < len(c.lits); k++ {
+				if s.value(c.lits[k]) != lFalse {
+					c.lits[1], c.lits[k] = c.lits[k], c.lits[1]
+					s.watches[c.lits[1].code()] = append(s.watches[c.lits[1].code()], c)
+					found = true
+					break
+				}
+			}
+			if found {
+				continue
+			}
+			ws[j] = c
+			j++
+			if s.value(c.lits[0]) == lFalse {
+				for i < len(ws) {
+					ws[j] = ws[i]
+					i++
+					j++
+				}
+				s.watches[falseLit.code()] = ws[:j]
+				s.qhead = len(s.trail)
+				return c
+			}
+			s.enqueue(c.lits[0], c)
+		}
+		s.watches[falseLit.code()] = ws[:j]
+	}
+	return nil
+}
+
+func (s *Solver) cancelUntil(level int) {
+	if s.decisionLevel() <= level {
+		return
+	}
+	for i := len(s.trail) - 1; i >= s.trailLim[level]; i-- {
+		v := s.trail[i].Var()
+		s.phase[v] = s.assigns[v] == lTrue
+		s.assigns[v] = lUndef
+		s.reason[v] = nil
+		if !s.order.contains(v) {
+			s.order.insert(v)
+		}
+	}
+	s.trail = s.trail[:s.trailLim[level]]
+	s.trailLim = s.trailLim[:level]
+	s.qhead = len(s.trail)
+}
+
+func (s *Solver) bumpVar(v int) {
+	s.activity[v] += s.varInc
+	if s.activity[v] > 1e100 {
+		for i := range s.activity {
+			s.activity[i] *= 1e-100
+		}
+		s.varInc *= 1e-100
+	}
+	if s.order.contains(v) {
+		s.order.up(s.order.index[v])
+	}
+}
+
+func (s *Solver) bumpClause(c *clause) {
+	c.activity += s.claInc
+	if c.activity > 1e20 {
+		for _, l := range s.learnts {
+			l.activity *= 1e-20
+		}
+		s.claInc *= 1e-20
+	}
+}
+
+// First UIP conflict analysis. Returns the learnt clause with the
+// asserting literal first and the level to backjump to.
+func (s *Solver) analyze(confl *clause) ([]Lit, int) {
+	learnt := []Lit{0}
+	pathC := 0
+	p := Lit(0)
+	idx := len(s.trail) - 1
+	for {
+		if confl.learnt {
+			s.bumpClause(confl)
+		}
+		start := 0
+		if p != 0 {
+			start = 1
+		}
+		for _, q := range confl.lits[start:] {
+			v := q.Var()
+			if s.seen[v] || s.level[v] == 0 {
+				continue
+			}
+			s.bumpVar(v)
+			s.seen[v] = true
+			if s.level[v] >= s.decisionLevel() {
+				pathC++
+			} else {
+				learnt = append(learnt, q)
+			}
+		}
+		for !s.seen[s.trail[idx].Var()] {
+			idx--
+		}
+		p = s.trail[idx]
+		idx--
+		confl = s.reason[p.Var()]
+		s.seen[p.Var()] = false
+		pathC--
+		if pathC == 0 {
+			break
+		}
+	}
+	learnt[0] = -p
+
+	// Drop literals implied by other literals of the clause.
+	all := append([]Lit(nil), learnt...)
+	j := 1
+	for _, q := range learnt[1:] {
+		r := s.reason[q.Var()]
+		keep := r == nil
+		if !keep {
+			for _, x := range r.lits[1:] {
+				if !s.seen[x.Var()] && s.level[x.Var()] > 0 {
+					keep = true
+					break
+				}
+			}
+		}
+		if keep {
+			learnt[j] = q
+			j++
+		}
+	}
+	learnt = learnt[:j]
+	for _, q := range all {
+		s.seen[q.Var()] = false
+	}
+
+	btlevel := 0
+	if len(learnt) > 1 {
+		max := 1
+		for i := 2; i < len(learnt); i++ {
+			if s.level[learnt[i].Var()] > s.level[learnt[max].Var()] {
+				max = i
+			}
+		}
+		learnt[1], learnt[max] = learnt[max], learnt[1]
+		btlevel = s.level[learnt[1].Var()]
+	}
+	return learnt, btlevel
+}
+
+func (s *Solver) computeLBD(lits []Lit) int {
+	levels := make(map[int]bool)
+	for _, l := range lits {
+		levels[s.level[l.Var()]] = true
+	}
+	return len(levels)
+}
+
+func (s *Solver) locked(c *clause) bool {
+	v := c.lits[0].Var()
+	return s.reason[v] == c && s.value(c.lits[0]) == lTrue
+}
+
+// Removes half of the learnt clauses, keeping those with small LBD,
+// high activity and those currently acting as reasons.
+func (s *Solver) reduceDB() {
+	sort.Sort(byUsefulness(s.learnts))
+	limit := len(s.learnts) / 2
+	j := 0
+	for i, c := range s.learnts {
+		if i < limit && c.lbd > 2 && len(c.lits) > 2 && !s.locked(c) {
+			s.removeClause(c)
+			continue
+		}
+		s.learnts[j] = c
+		j++
+	}
+	s.learnts = s.learnts[:j]
+}
+
+func (s *Solver) removeClause(c *clause) {
+	s.trace("d ", c.lits)
+	c.deleted = true
+}
+
+// Writes a clause addition (prefix "") or deletion (prefix "d ")
+// to the proof.
+func (s *Solver) trace(prefix string, lits []Lit) {
+	if s.Proof == nil {
+		return
+	}
+	b := []byte(prefix)
+	for _, l := range lits {
+		b = strconv.AppendInt(b, int64(l), 10)
+		b = append(b, ' ')
+	}
+	b = append(b, '0', '\n')
+	s.Proof.Write(b)
+}
+
+func (s *Solver) pickBranchLit() Lit {
+	if s.opts.RandomFreq > 0 && !s.order.empty() && s.rng.Float64() < s.opts.RandomFreq {
+		v := s.order.heap[s.rng.Intn(len(s.order.heap))]
+		if s.assigns[v] == lUndef {
+			s.Decisions++
+			if s.phase[v] {
+				return Lit(v)
+			}
+			return Lit(-v)
+		}
+	}
+	for !s.order.empty() {
+		v := s.order.removeMax()
+		if s.assigns[v] == lUndef {
+			s.Decisions++
+			if s.phase[v] {
+				return Lit(v)
+			}
+			return Lit(-v)
+		}
+	}
+	return 0
+}
+
+// Runs the search until a model is found, the formula is refuted or
+// nconflicts conflicts occurred (lUndef).
+func (s *Solver) search(nconflicts int) int8 {
+	conflicts := 0
+	for steps := 1; ; steps++ {
+		confl := s.propagate()
+		if confl != nil {
+			s.Conflicts++
+			conflicts++
+			if s.decisionLevel() == 0 {
+				return lFalse
+			}
+			learnt, btlevel := s.analyze(confl)
+			s.trace("", learnt)
+			if s.Export != nil {
+				s.Export(append([]Lit(nil), learnt...))
+			}
+			s.cancelUntil(btlevel)
+			if len(learnt) == 1 {
+				s.enqueue(learnt[0], nil)
+			} else {
+				c := &clause{lits: learnt, learnt: true, lbd: s.computeLBD(learnt)}
+				s.learnts = append(s.learnts, c)
+				s.attach(c)
+				s.bumpClause(c)
+				s.enqueue(learnt[0], c)
+			}
+			s.varInc /= 0.95
+			s.claInc /= 0.999
+			continue
+		}
+
+		if nconflicts >= 0 && conflicts >= nconflicts {
+			s.cancelUntil(0)
+			return lUndef
+		}
+		if s.ctx != nil && steps%1024 == 0 && s.ctx.Err() != nil {
+			s.cancelUntil(0)
+			return lUndef
+		}
+		if float64(len(s.learnts)-len(s.trail)) >= s.maxLearnts {
+			s.reduceDB()
+			s.maxLearnts *= 1.1
+		}
+
+		next := Lit(0)
+		for s.decisionLevel() < len(s.assumptions) {
+			p := s.assumptions[s.decisionLevel()]
+			if s.value(p) == lTrue {
+				s.trailLim = append(s.trailLim, len(s.trail))
+				continue
+			}
+			if s.value(p) == lFalse {
+				s.failedAssumption = true
+				return lFalse
+			}
+			next = p
+			break
+		}
+		if next == 0 {
+			next = s.pickBranchLit()
+			if next == 0 {
+				return lTrue
+			}
+		}
+		s.trailLim = append(s.trailLim, len(s.trail))
+		s.enqueue(next, nil)
+	}
+}
+
+// Solve decides satisfiability under the given assumptions. After a
+// satisfiable result, the model is available through Model.
+func (s *Solver) Solve(assumptions ...Lit) bool {
+	r, _ := s.SolveContext(context.Background(), assumptions...)
+	return r
+}
+
+// SolveContext is like Solve, but gives up with the context's error
+// once ctx is done.
+func (s *Solver) SolveContext(ctx context.Context, assumptions ...Lit) (bool, error) {
+	s.model = nil
+	if !s.ok {
+		return false, nil
+	}
+	s.ctx = ctx
+	for _, l := range assumptions {
+		s.ensureVars(l.Var())
+	}
+	s.assumptions = assumptions
+	s.failedAssumption = false
+	defer func() {
+		s.assumptions = nil
+	}()
+	if s.maxLearnts == 0 {
+		s.maxLearnts = float64(len(s.clauses))/3 + 1000
+	}
+
+	status := lUndef
+	for i := 0; status == lUndef; i++ {
+		if e := ctx.Err(); e != nil {
+			return false, e
+		}
+		if !s.importClauses() {
+			status = lFalse
+			break
+		}
+		status = s.search(s.restartLimit(i))
+	}
+	if status == lTrue {
+		s.model = make([]bool, s.nvars+1)
+		for v := 1; v <= s.nvars; v++ {
+			s.model[v] = s.assigns[v] == lTrue
+		}
+	} else if !s.failedAssumption {
+		s.trace("", nil)
+		s.ok = false
+	}
+	s.cancelUntil(0)
+	return status == lTrue, nil
+}
+
+// Number of conflicts before the i-th restart, -1 for no limit.
+func (s *Solver) restartLimit(i int) int {
+	switch s.opts.Restarts {
+	case GeometricRestarts:
+		r := 100.0
+		for ; i > 0 && r < 1e9; i-- {
+			r *= 1.5
+		}
+		return int(r)
+	case NoRestarts:
+		return -1
+	}
+	return 100 * luby(i)
+}
+
+// Adds the clauses returned by Import on level 0. Returns false if
+// they make the formula unsatisfiable.
+func (s *Solver) importClauses() bool {
+	if s.Import == nil {
+		return true
+	}
+	for _, lits := range s.Import() {
+		c := make([]Lit, 0, len(lits))
+		satisfied := false
+		for _, l := range lits {
+			s.ensureVars(l.Var())
+			switch s.value(l) {
+			case lTrue:
+				satisfied = true
+			case lUndef:
+				c = append(c, l)
+			}
+		}
+		if satisfied {
+			continue
+		}
+		switch len(c) {
+		case 0:
+			return false
+		case 1:
+			s.enqueue(c[0], nil)
+			if s.propagate() != nil {
+				return false
+			}
+		default:
+			cl := &clause{lits: c, learnt: true, lbd: len(c)}
+			s.learnts = append(s.learnts, cl)
+			s.attach(cl)
+		}
+	}
+	return true
+}
+
+// Model returns the last model found, indexed by variable. Index 0
+// is unused.
+func (s *Solver) Model() []bool {
+	return s.model
+}
+
+// Finite Luby sequence: 1 1 2 1 1 2 4 1 1 2 ...
+func luby(i int) int {
+	size, seq := 1, 0
+	for size < i+1 {
+		seq++
+		size = 2*size + 1
+	}
+	x := i
+	for size-1 != x {
+		size = (size - 1) >> 1
+		seq--
+		x = x % size
+	}
+	r := 1
+	for ; seq > 0; seq-- {
+		r *= 2
+	}
+	return r
+}
+
+type litSlice []Lit
+
+func (l litSlice) Len() int {
+	return len(l)
+}
+
+func (l litSlice) Less(i, j int) bool {
+	return litLess(l[i], l[j])
+}
+
+// Orders literals by variable, negative before positive.
+func litLess(a, b Lit) bool {
+	if a.Var() != b.Var() {
+		return a.Var() < b.Var()
+	}
+	return a < b
+}
+
+func (l litSlice) Swap(i, j int) {
+	l[i], l[j] = l[j], l[i]
+}
+
+type byUsefulness []*clause
+
+func (c byUsefulness) Len() int {
+	return len(c)
+}
+
+func (c byUsefulness) Less(i, j int) bool {
+	if c[i].lbd != c[j].lbd {
+		return c[i].lbd > c[j].lbd
+	}
+	return c[i].activity < c[j].activity
+}
+
+func (c byUsefulness) Swap(i, j int) {
+	c[i], c[j] = c[j], c[i]
+}
+
+// Binary max-heap of variables ordered by activity.
+type varHeap struct {
+	heap     []int
+	index    []int
+	activity *[]float64
+}
+
+func (h *varHeap) less(a, b int) bool {
+	return (*h.activity)[h.heap[a]] > (*h.activity)[h.heap[b]]
+}
+
+func (h *varHeap) swap(a, b int) {
+	h.heap[a], h.heap[b] = h.heap[b], h.heap[a]
+	h.index[h.heap[a]] = a
+	h.index[h.heap[b]] = b
+}
+
+func (h *varHeap) up(i int) {
+	for i > 0 {
+		parent := (i - 1) / 2
+		if !h.less(i, parent) {
+			break
+		}
+		h.swap(i, parent)
+		i = parent
+	}
+}
+
+func (h *varHeap) down(i int) {
+	for {
+		child := 2*i + 1
+		if child >= len(h.heap) {
+			break
+		}
+		if child+1 < len(h.heap) && h.less(child+1, child) {
+			child++
+		}
+		if !h.less(child, i) {
+			break
+		}
+		h.swap(i, child)
+		i = child
+	}
+}
+
+func (h *varHeap) contains(v int) bool {
+	return h.index[v] >= 0
+}
+
+func (h *varHeap) empty() bool {
+	return len(h.heap) == 0
+}
+
+func (h *varHeap) insert(v int) {
+	h.index[v] = len(h.heap)
+	h.heap = append(h.heap, v)
+	h.up(len(h.heap) - 1)
+}
+
+func (h *varHeap) removeMax() int {
+	v := h.heap[0]
+	last := len(h.heap) - 1
+	h.swap(0, last)
+	h.heap = h.heap[:last]
+	h.index[v] = -1
+	if len(h.heap) > 0 {
+		h.down(0)
+	}
+	return v
+}
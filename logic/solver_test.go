@@ -0,0 +1,94 @@
+package logic
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func randomClauseSet(rng *rand.Rand, nvars, nclauses, k int) *ClauseSet {
+	cs := NewClauseSet()
+	for v := 1; v <= nvars; v++ {
+		cs.Var(string(rune('a' + v - 1)))
+	}
+	for i := 0; i < nclauses; i++ {
+		c := make(Clause, k)
+		for j := range c {
+			c[j] = Lit(rng.Intn(nvars) + 1)
+			if rng.Intn(2) == 0 {
+				c[j] = -c[j]
+			}
+		}
+		cs.AddClause(c...)
+	}
+	return cs
+}
+
+// Calls f for every assignment of the variables of cs.
+func allModels(cs *ClauseSet, f func([]bool)) {
+	model := make([]bool, cs.NumVars+1)
+	for i := 0; i < 1<<uint(cs.NumVars); i++ {
+		for v := 1; v <= cs.NumVars; v++ {
+			model[v] = i&(1<<uint(v-1)) != 0
+		}
+		if cs.Satisfies(model) {
+			f(model)
+		}
+	}
+}
+
+func TestSolver(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 300; i++ {
+		cs := randomClauseSet(rng, 8, 10+rng.Intn(40), 3)
+		sat := false
+		allModels(cs, func([]bool) { sat = true })
+		s := NewSolverFromClauses(cs)
+		if s.Solve() != sat {
+			t.Fatalf("Solver returned %v for %s", !sat, cs.DIMACS())
+		}
+		if sat && !cs.Satisfies(s.Model()) {
+			t.Fatalf("Invalid model for %s", cs.DIMACS())
+		}
+	}
+}
+
+func TestSolverAssumptions(t *testing.T) {
+	// a => b, b => c
+	s := NewSolver(3)
+	s.AddClause(-1, 2)
+	s.AddClause(-2, 3)
+	if !s.Solve(1) || !s.Model()[3] {
+		t.Fatalf("Assuming a must yield c")
+	}
+	if s.Solve(1, -3) {
+		t.Fatalf("a and !c must be unsatisfiable")
+	}
+	if !s.Solve(-3) || s.Model()[1] {
+		t.Fatalf("Failed assumptions must not make the solver unsatisfiable")
+	}
+	s.AddClause(1)
+	if s.Solve(-3) {
+		t.Fatalf("Added unit clause is ignored")
+	}
+}
+
+func TestSolverPigeonhole(t *testing.T) {
+	// 5 pigeons, 4 holes
+	p := func(i, j int) Lit {
+		return Lit(i*4 + j + 1)
+	}
+	s := NewSolver(20)
+	for i := 0; i < 5; i++ {
+		s.AddClause(p(i, 0), p(i, 1), p(i, 2), p(i, 3))
+	}
+	for j := 0; j < 4; j++ {
+		for i := 0; i < 5; i++ {
+			for k := i + 1; k < 5; k++ {
+				s.AddClause(-p(i, j), -p(k, j))
+			}
+		}
+	}
+	if s.Solve() {
+		t.Fatalf("Pigeonhole formula is satisfiable")
+	}
+}
@@ -0,0 +1,146 @@
+package logic
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+)
+
+// Stats describes the size of a formula and of its conversions.
+// Subformulas are counted once per occurrence.
+type Stats struct {
+	Nodes, Leafs, Operations int
+	// Leafs are at depth 0
+	Depth int
+	// Number of distinct leaf names
+	Vars int
+	// Number of operations per operator
+	Operators map[string]int
+	// Size of CNF(n) and Clauses(n)
+	CNFClauses, CNFLiterals *big.Int
+	// Size of TseitinClauses(n)
+	TseitinVars, TseitinClauses, TseitinLiterals int
+}
+
+// Analyze computes the statistics of n without converting it to CNF.
+// The Tseitin encoding is built, as it is only linear in n. AND and
+// OR without operands count as true and false, otherwise it panics
+// where Eval would.
+func Analyze(n Node) *Stats {
+	s := &Stats{Operators: make(map[string]int)}
+	s.Depth = s.count(n)
+	s.Vars = len(DefaultMap(n))
+	s.CNFClauses, s.CNFLiterals = CNFSize(n)
+	cs := TseitinClauses(n)
+	s.TseitinVars, s.TseitinClauses = cs.NumVars, len(cs.Clauses)
+	for _, c := range cs.Clauses {
+		s.TseitinLiterals += len(c)
+	}
+	return s
+}
+
+// Counts the nodes of n and returns its depth.
+func (s *Stats) count(n Node) int {
+	s.Nodes++
+	x, ok := n.(*Operation)
+	if !ok {
+		s.Leafs++
+		return 0
+	}
+	mustOperatorOrConstant(x)
+	s.Operations++
+	s.Operators[x.Operator]++
+	depth := 0
+	for _, operand := range x.Operands {
+		if d := s.count(operand); d > depth {
+			depth = d
+		}
+	}
+	return depth + 1
+}
+
+func (s *Stats) String() string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "Nodes: %d (%d leafs, %d operations)\n", s.Nodes, s.Leafs, s.Operations)
+	fmt.Fprintf(&b, "Depth: %d\n", s.Depth)
+	fmt.Fprintf(&b, "Variables: %d\n", s.Vars)
+	for _, op := range Operators() {
+		if s.Operators[op] > 0 {
+			fmt.Fprintf(&b, "  %s: %d\n", op, s.Operators[op])
+		}
+	}
+	fmt.Fprintf(&b, "CNF: %s clauses, %s literals\n", s.CNFClauses, s.CNFLiterals)
+	fmt.Fprintf(&b, "Tseitin: %d variables, %d clauses, %d literals", s.TseitinVars, s.TseitinClauses, s.TseitinLiterals)
+	return b.String()
+}
+
+type cnfSize struct {
+	clauses, literals *big.Int
+}
+
+type cnfSizer map[*Operation][2]cnfSize
+
+// CNFSize returns the number of clauses and literals CNF(n) would
+// have. It follows the rewrites of Simplify, DeMorgan and CNF
+// without building anything, so it takes time linear in n even if
+// the result is huge. It panics where Eval would.
+func CNFSize(n Node) (clauses, literals *big.Int) {
+	r := make(cnfSizer).size(n, true)
+	return r.clauses, r.literals
+}
+
+// Returns the size of CNF(n) if positive, else of CNF(!n).
+func (c cnfSizer) size(n Node, positive bool) cnfSize {
+	x, ok := n.(*Operation)
+	if !ok {
+		return cnfSize{big.NewInt(1), big.NewInt(1)}
+	}
+	polarity := 0
+	if !positive {
+		polarity = 1
+	}
+	if r, ok := c[x]; ok && r[polarity].clauses != nil {
+		return r[polarity]
+	}
+	op := mustOperatorOrConstant(x)
+	var r cnfSize
+	switch {
+	case op.Rewrite != nil:
+		r = c.size(op.Rewrite(x.Operands), positive)
+	case x.Operator == NOT:
+		r = c.size(x.Operands[0], !positive)
+	case len(x.Operands) == 1:
+		r = c.size(x.Operands[0], positive)
+	case (x.Operator == AND) == positive:
+		// Conjunction: clauses of the operands side by side
+		r = cnfSize{new(big.Int), new(big.Int)}
+		for _, operand := range x.Operands {
+			s := c.size(operand, positive)
+			r.clauses.Add(r.clauses, s.clauses)
+			r.literals.Add(r.literals, s.literals)
+		}
+	default:
+		// Disjunction: one clause for every choice of a clause per
+		// operand. The literals of operand i appear in every
+		// combination with the clauses of the others.
+		sizes := make([]cnfSize, len(x.Operands))
+		r = cnfSize{big.NewInt(1), new(big.Int)}
+		for i, operand := range x.Operands {
+			sizes[i] = c.size(operand, positive)
+			r.clauses.Mul(r.clauses, sizes[i].clauses)
+		}
+		for i := range sizes {
+			l := new(big.Int).Set(sizes[i].literals)
+			for j := range sizes {
+				if j != i {
+					l.Mul(l, sizes[j].clauses)
+				}
+			}
+			r.literals.Add(r.literals, l)
+		}
+	}
+	both := c[x]
+	both[polarity] = r
+	c[x] = both
+	return r
+}
@@ -0,0 +1,79 @@
+package logic
+
+import (
+	"testing"
+)
+
+func TestAnalyze(t *testing.T) {
+	a, b, c := NewLeaf("a"), NewLeaf("b"), NewLeaf("c")
+	n := NewOperation(OR, NewOperation(AND, a, b), NewOperation(AND, b, c), NewOperation(NOT, a))
+	s := Analyze(n)
+	if s.Nodes != 9 || s.Leafs != 5 || s.Operations != 4 || s.Depth != 2 || s.Vars != 3 {
+		t.Fatalf("Wrong counts:\n%s", s)
+	}
+	if s.Operators[AND] != 2 || s.Operators[OR] != 1 || s.Operators[NOT] != 1 {
+		t.Fatalf("Wrong histogram: %v", s.Operators)
+	}
+	// (a v b v !a) ^ (a v c v !a) ^ (b v b v !a) ^ (b v c v !a)
+	if s.CNFClauses.Int64() != 4 || s.CNFLiterals.Int64() != 12 {
+		t.Fatalf("Wrong CNF size:\n%s", s)
+	}
+	if s.TseitinVars != 6 || s.TseitinClauses != 11 {
+		t.Fatalf("Wrong Tseitin size:\n%s", s)
+	}
+}
+
+func TestAnalyzeConstants(t *testing.T) {
+	// An empty AND is true, an empty OR false
+	n := NewOperation(AND, NewOperation(OR), NewOperation(OR, NewOperation(AND), NewLeaf("a")))
+	s := Analyze(n)
+	if s.Operations != 4 || s.Leafs != 1 {
+		t.Fatalf("Wrong counts:\n%s", s)
+	}
+	// The empty clause, as true v a needs none
+	if s.CNFClauses.Int64() != 1 || s.CNFLiterals.Int64() != 0 {
+		t.Fatalf("Wrong CNF size:\n%s", s)
+	}
+	cs := Clauses(n)
+	if len(cs.Clauses) != 1 || len(cs.Clauses[0]) != 0 {
+		t.Fatalf("Clauses(%s) = %v", n, cs.Clauses)
+	}
+	for _, x := range []Node{
+		n,
+		NewOperation(OR, NewOperation(AND), NewLeaf("a")),
+		NewOperation(NOT, NewOperation(OR, NewOperation(OR), NewOperation(NOT, NewLeaf("a")))),
+		NewOperation(IFF, NewOperation(AND), NewOperation(XOR, NewLeaf("a"), NewOperation(OR))),
+	} {
+		if ok, c := Equivalent(x, CNF(x)); !ok {
+			t.Fatalf("CNF(%s) = %s differs on %v", x, CNF(x), c)
+		}
+	}
+}
+
+func TestCNFSize(t *testing.T) {
+	g := NewGenerator(4, GeneratorOptions{Vars: 4, Depth: 3})
+	for i := 0; i < 300; i++ {
+		n := g.Formula()
+		cnf := CNF(n).(*Operation)
+		literals := 0
+		for _, c := range cnf.Operands {
+			literals += len(c.(*Operation).Operands)
+		}
+		clauses, lits := CNFSize(n)
+		if clauses.Int64() != int64(len(cnf.Operands)) || lits.Int64() != int64(literals) {
+			t.Fatalf("CNFSize(%s) = %s, %s, but CNF has %d clauses and %d literals", n, clauses, lits, len(cnf.Operands), literals)
+		}
+	}
+}
+
+func TestCNFSizeLarge(t *testing.T) {
+	// (x1 ^ y1) v ... v (x40 ^ y40) has 2^40 clauses of 40 literals
+	or := NewOperation(OR)
+	for i := 0; i < 40; i++ {
+		or.PushOperands(NewOperation(AND, NewLeaf(VarName(2*i)), NewLeaf(VarName(2*i+1))))
+	}
+	clauses, literals := CNFSize(or)
+	if clauses.Int64() != 1<<40 || literals.Int64() != 40<<40 {
+		t.Fatalf("CNFSize returned %s, %s", clauses, literals)
+	}
+}
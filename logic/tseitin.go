@@ -0,0 +1,61 @@
+package logic
+
+import "fmt"
+
+type tseitin struct {
+	cs   *ClauseSet
+	done map[*Operation]Lit
+}
+
+// Tseitin adds clauses to cs defining a fresh variable for every
+// operation of n and returns the literal equivalent to n. Leafs use
+// the variables of their names, the new variables are anonymous. The
+// operators are encoded with the semantics of Eval, so IF and IFF
+// fold from the left. Operators with an Encode function use it,
+// all others are rewritten first. It panics where Eval would.
+func (cs *ClauseSet) Tseitin(n Node) Lit {
+	t := &tseitin{cs: cs, done: make(map[*Operation]Lit)}
+	return t.encode(n)
+}
+
+// TseitinClauses returns the Tseitin encoding of n with n asserted.
+// It has the same models as n when restricted to the leafs, but
+// grows only linearly with n.
+func TseitinClauses(n Node) *ClauseSet {
+	cs := NewClauseSet()
+	cs.AddClause(cs.Tseitin(n))
+	return cs
+}
+
+// NewVar allocates an anonymous variable.
+func (cs *ClauseSet) NewVar() Lit {
+	cs.grow(cs.NumVars + 1)
+	return Lit(cs.NumVars)
+}
+
+func (t *tseitin) encode(n Node) Lit {
+	switch x := n.(type) {
+	case Leaf:
+		return Lit(t.cs.Var(string(x)))
+	case *Operation:
+		if l, ok := t.done[x]; ok {
+			return l
+		}
+		op := mustOperatorOrConstant(x)
+		var out Lit
+		if op.Encode == nil {
+			// The rewrite shares the operands, so they are still
+			// encoded only once.
+			out = t.encode(op.Rewrite(x.Operands))
+		} else {
+			ins := make([]Lit, len(x.Operands))
+			for i, operand := range x.Operands {
+				ins[i] = t.encode(operand)
+			}
+			out = op.Encode(t.cs, ins)
+		}
+		t.done[x] = out
+		return out
+	}
+	panic(fmt.Sprintf("Cannot encode %v", n))
+}
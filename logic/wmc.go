@@ -0,0 +1,199 @@
+package logic
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"strings"
+)
+
+// Weights assigns a weight to literals. Literals without a weight
+// have weight 1.
+type Weights map[Lit]*big.Rat
+
+var one = big.NewRat(1, 1)
+
+func (w Weights) Of(l Lit) *big.Rat {
+	if r, ok := w[l]; ok {
+		return r
+	}
+	return one
+}
+
+// SetProbability gives v the weight p and -v the weight 1-p.
+func (w Weights) SetProbability(v int, p *big.Rat) {
+	w[Lit(v)] = new(big.Rat).Set(p)
+	w[Lit(-v)] = new(big.Rat).Sub(one, p)
+}
+
+// ReadProbabilities reads lines of a name and a probability, given
+// as a decimal or a fraction. Empty lines and lines starting with #
+// are skipped.
+func ReadProbabilities(r io.Reader) (map[string]*big.Rat, error) {
+	sc := bufio.NewScanner(r)
+	probs := make(map[string]*big.Rat)
+	for lineno := 1; sc.Scan(); lineno++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("Line %d: expected name and probability", lineno)
+		}
+		p, ok := new(big.Rat).SetString(fields[1])
+		if !ok || p.Sign() < 0 || p.Cmp(one) > 0 {
+			return nil, fmt.Errorf("Line %d: invalid probability %s", lineno, fields[1])
+		}
+		probs[fields[0]] = p
+	}
+	return probs, sc.Err()
+}
+
+type wmcCounter struct {
+	w         Weights
+	projected []bool
+	cache     map[string]*big.Rat
+}
+
+// WeightedCount returns the sum of the weights of the assignments of
+// the variables in projection which can be extended to a model of cs.
+// The weight of an assignment is the product of the weights of its
+// literals. A nil projection means all variables. The count is exact;
+// with probabilities as weights it is the probability that cs is
+// satisfiable given independently distributed projected variables.
+func WeightedCount(cs *ClauseSet, w Weights, projection []int) *big.Rat {
+	c := &wmcCounter{
+		w:         w,
+		projected: make([]bool, cs.NumVars+1),
+		cache:     make(map[string]*big.Rat),
+	}
+	vars := make([]int, 0)
+	if projection == nil {
+		for v := 1; v <= cs.NumVars; v++ {
+			vars = append(vars, v)
+		}
+	} else {
+		vars = append(vars, projection...)
+		sort.Ints(vars)
+	}
+	for _, v := range vars {
+		c.projected[v] = true
+	}
+	return c.count(normalizeClauses(cs), vars)
+}
+
+// Counts clauses over the given projected variables.
+func (c *wmcCounter) count(clauses []Clause, vars []int) *big.Rat {
+	clauses, units, ok := propagateUnits(clauses)
+	if !ok {
+		return new(big.Rat)
+	}
+	r := big.NewRat(1, 1)
+	assigned := make(map[int]bool)
+	for _, l := range units {
+		if c.projected[l.Var()] {
+			r.Mul(r, c.w.Of(l))
+		}
+		assigned[l.Var()] = true
+	}
+	components, covered := splitComponents(clauses)
+	for _, v := range vars {
+		if !assigned[v] && !covered[v] {
+			r.Mul(r, new(big.Rat).Add(c.w.Of(Lit(v)), c.w.Of(Lit(-v))))
+		}
+	}
+	for _, comp := range components {
+		if r.Sign() == 0 {
+			break
+		}
+		r.Mul(r, c.component(comp))
+	}
+	return r
+}
+
+// Branches on the most frequent projected variable of a connected
+// component. Without projected variables only satisfiability
+// matters.
+func (c *wmcCounter) component(comp []Clause) *big.Rat {
+	key := componentKey(comp)
+	if r, ok := c.cache[key]; ok {
+		return r
+	}
+	count := make(map[int]int)
+	nvars := 0
+	for _, cl := range comp {
+		for _, l := range cl {
+			if c.projected[l.Var()] {
+				count[l.Var()]++
+			}
+			if l.Var() > nvars {
+				nvars = l.Var()
+			}
+		}
+	}
+	r := new(big.Rat)
+	if len(count) == 0 {
+		s := NewSolverFromClauses(&ClauseSet{NumVars: nvars, Clauses: comp})
+		if s.Solve() {
+			r.SetInt64(1)
+		}
+		c.cache[key] = r
+		return r
+	}
+
+	vars := make([]int, 0, len(count))
+	for v := range count {
+		vars = append(vars, v)
+	}
+	sort.Ints(vars)
+	best := vars[0]
+	for _, v := range vars {
+		if count[v] > count[best] {
+			best = v
+		}
+	}
+	rest := make([]int, 0, len(vars)-1)
+	for _, v := range vars {
+		if v != best {
+			rest = append(rest, v)
+		}
+	}
+	for _, l := range []Lit{Lit(best), Lit(-best)} {
+		if c.w.Of(l).Sign() == 0 {
+			continue
+		}
+		sub := c.count(condition(comp, l), rest)
+		r.Add(r, sub.Mul(sub, c.w.Of(l)))
+	}
+	c.cache[key] = r
+	return r
+}
+
+// WeightedCount returns the sum of the weights of all models.
+func (d *DNNF) WeightedCount(w Weights) *big.Rat {
+	counts := make([]*big.Rat, len(d.Nodes))
+	for i, n := range d.Nodes {
+		switch n.Kind {
+		case DNNFTrue:
+			counts[i] = big.NewRat(1, 1)
+		case DNNFFalse:
+			counts[i] = new(big.Rat)
+		case DNNFLit:
+			counts[i] = w.Of(n.Lit)
+		case DNNFAnd:
+			counts[i] = big.NewRat(1, 1)
+			for _, child := range n.Children {
+				counts[i].Mul(counts[i], counts[child])
+			}
+		case DNNFOr:
+			counts[i] = new(big.Rat)
+			for _, child := range n.Children {
+				counts[i].Add(counts[i], counts[child])
+			}
+		}
+	}
+	return counts[d.Root]
+}
@@ -0,0 +1,100 @@
+package logic
+
+import (
+	"math/big"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestWeightedCount(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		cs := randomClauseSet(rng, 8, rng.Intn(30), 3)
+		w := make(Weights)
+		for v := 1; v <= cs.NumVars; v++ {
+			w.SetProbability(v, big.NewRat(int64(rng.Intn(11)), 10))
+		}
+		projection := make([]int, 0)
+		for v := 1; v <= cs.NumVars; v++ {
+			if rng.Intn(2) == 0 {
+				projection = append(projection, v)
+			}
+		}
+
+		// Sum over the assignments of the projection with a model.
+		seen := make(map[int]bool)
+		expected := new(big.Rat)
+		allModels(cs, func(m []bool) {
+			key := 0
+			for _, v := range projection {
+				if m[v] {
+					key |= 1 << uint(v)
+				}
+			}
+			if seen[key] {
+				return
+			}
+			seen[key] = true
+			weight := big.NewRat(1, 1)
+			for _, v := range projection {
+				l := Lit(v)
+				if !m[v] {
+					l = -l
+				}
+				weight.Mul(weight, w.Of(l))
+			}
+			expected.Add(expected, weight)
+		})
+		if r := WeightedCount(cs, w, projection); r.Cmp(expected) != 0 {
+			t.Fatalf("WeightedCount of %s on %v is %s, expected %s", cs.DIMACS(), projection, r, expected)
+		}
+
+		all := new(big.Rat)
+		allModels(cs, func(m []bool) {
+			weight := big.NewRat(1, 1)
+			for v := 1; v <= cs.NumVars; v++ {
+				l := Lit(v)
+				if !m[v] {
+					l = -l
+				}
+				weight.Mul(weight, w.Of(l))
+			}
+			all.Add(all, weight)
+		})
+		if r := WeightedCount(cs, w, nil); r.Cmp(all) != 0 {
+			t.Fatalf("WeightedCount of %s is %s, expected %s", cs.DIMACS(), r, all)
+		}
+		if r := CompileDNNF(cs).WeightedCount(w); r.Cmp(all) != 0 {
+			t.Fatalf("DNNF WeightedCount of %s is %s, expected %s", cs.DIMACS(), r, all)
+		}
+	}
+}
+
+func TestWeightedCountSmallProbabilities(t *testing.T) {
+	// Conjunction of 200 variables with probability 1/1000 each.
+	cs := NewClauseSet()
+	w := make(Weights)
+	for i := 0; i < 200; i++ {
+		v := cs.Var(strings.Repeat("x", i+1))
+		cs.AddClause(Lit(v))
+		w.SetProbability(v, big.NewRat(1, 1000))
+	}
+	expected := new(big.Rat).SetFrac(big.NewInt(1), new(big.Int).Exp(big.NewInt(1000), big.NewInt(200), nil))
+	if r := WeightedCount(cs, w, nil); r.Cmp(expected) != 0 {
+		t.Fatalf("WeightedCount is %s", r)
+	}
+}
+
+func TestReadProbabilities(t *testing.T) {
+	probs, e := ReadProbabilities(strings.NewReader("# availability\n1 0.25\n\n3 1/3\n"))
+	if e != nil {
+		t.Fatal(e)
+	}
+	if probs["1"].Cmp(big.NewRat(1, 4)) != 0 || probs["3"].Cmp(big.NewRat(1, 3)) != 0 {
+		t.Fatalf("ReadProbabilities returned %v", probs)
+	}
+	if _, e := ReadProbabilities(strings.NewReader("1 1.5\n")); e == nil {
+		t.Fatal("Probability above 1 accepted")
+	}
+}
@@ -2,9 +2,12 @@ package main
 
 import (
 	"./logic"
+	"./stoichio"
 	"bufio"
+	"context"
 	"flag"
 	"fmt"
+	"math/big"
 	"os"
 	"regexp"
 	"strconv"
@@ -12,19 +15,98 @@ import (
 )
 
 var (
-	input = flag.String("i", "", "Input to read from.")
-	sat   = flag.Bool("s", false, "Produce SAT compatible output")
+	input    = flag.String("i", "", "Input to read from, SBML if the name ends in .xml or .sbml.")
+	sat      = flag.Bool("s", false, "Produce SAT compatible output")
+	backbone = flag.Bool("b", false, "List essential and blocked reactions")
+	reaction = flag.Int("r", 0, "Force the given reaction (1-based) to be active")
+	jobs     = flag.Int("j", 0, "Solve with this many parallel solvers and print the active reactions")
+	save     = flag.String("save", "", "Save the formula to this file (JSON if the name ends in .json, else binary)")
+	avail    = flag.String("a", "", "File of reaction numbers (1-based) and probabilities; print the probability that a flux mode exists")
+	maxcnf   = flag.Int64("max-clauses", 10000000, "Refuse to convert formulas whose CNF has more clauses")
+	force    = flag.Bool("f", false, "Convert to CNF regardless of -max-clauses")
+	notation = flag.String("notation", "ascii", "Print the formula as ascii, unicode or latex")
+	width    = flag.Int("width", 0, "Break the formula printed with -notation into lines of this width")
+	efm      = flag.Bool("efm", false, "Enumerate the elementary flux modes exactly and print their supports as solutionfilter reads them")
+	fluxes   = flag.Bool("fluxes", false, "With -efm, print the flux vectors instead of the supports")
+	ranktest = flag.Bool("rank-test", false, "With -efm, test elementarity by rank instead of by comparing supports")
+	fca      = flag.Bool("fca", false, "Print the flux coupling of all pairs of reactions, computed from the elementary modes, and confirm it with the SAT encoding")
+	fba      = flag.Int("fba", 0, "Maximize the flux through this reaction (1-based) and print the fluxes, shadow prices and reduced costs")
+	bound    = flag.Float64("bound", 1000, "Bound of the absolute flux of every reaction for -fba and -fva")
+	fva      = flag.Bool("fva", false, "Print the flux range of every reaction, with -fba at -fraction of the optimum")
+	fraction = flag.Float64("fraction", 1, "Fraction of the optimum of -fba the fluxes of -fva have to reach")
+	workers  = flag.Int("workers", 0, "Number of LPs -fva solves in parallel, the number of CPUs if 0")
+	compress = flag.Bool("compress", false, "Remove blocked reactions and merge enzyme subsets first; -efm, -j and -b report the original reactions")
 )
 
 func main() {
 	flag.Parse()
 
-	matrixstring, irreversiblestring := ReadInputStrings()
-	stoichio := ParseMatrix(matrixstring)
-	irreversible := ParseIrreversible(irreversiblestring)
-	checkSanity(stoichio, irreversible)
-	l := generateLogic(stoichio, irreversible)
-	fmt.Printf("Logic:\n%s\n", l)
+	network := readNetwork()
+	stoichio, irreversible := StoichioMatrix(network.Matrix), network.Irreversible()
+	if *fva {
+		printFVA(network, *fba-1)
+		return
+	}
+	if *fba != 0 {
+		printFBA(network, *fba-1)
+		return
+	}
+	c := compressNetwork(stoichio, irreversible)
+	if c != nil {
+		stoichio, irreversible = StoichioMatrix(c.Matrix), c.Irreversible
+	}
+	if *efm {
+		printModes(stoichio, irreversible, c, network.Scale)
+		return
+	}
+	if c != nil && len(irreversible) == 0 {
+		printAllBlocked(network, c)
+		return
+	}
+	leafs := leafNames(network, c)
+	l := generateLogic(stoichio, irreversible, leafs)
+	if *reaction != 0 {
+		if *reaction < 0 || *reaction > len(irreversible) {
+			panic("Forced reaction out of range")
+		}
+		l = logic.NewOperation(logic.AND, l, logic.NewLeaf(leafs[*reaction-1]))
+	}
+	if *save != "" {
+		if e := logic.SaveFormula(*save, l); e != nil {
+			panic("Could not save formula: " + e.Error())
+		}
+	}
+	stats := logic.Analyze(l)
+	fmt.Fprintf(os.Stderr, "Formula:\n%s\n", stats)
+	if !*force && stats.CNFClauses.Cmp(big.NewInt(*maxcnf)) > 0 {
+		panic(fmt.Sprintf("CNF would have %s clauses, use -f to convert anyway", stats.CNFClauses))
+	}
+	if *fca {
+		printCoupling(network, leafs, l)
+		return
+	}
+	if *backbone {
+		printBackbone(network, leafs, l, c)
+		return
+	}
+	if *jobs > 0 {
+		solve(network, leafs, l, *jobs, c)
+		return
+	}
+	if *avail != "" {
+		availability(l, *avail, leafs)
+		return
+	}
+	if *notation == "ascii" {
+		fmt.Printf("Logic:\n%s\n", l)
+	} else {
+		n, ok := logic.Notations[*notation]
+		if !ok {
+			panic("Unknown notation " + *notation)
+		}
+		p := &logic.Printer{Notation: n, Width: *width}
+		fmt.Printf("Logic:\n%s\n", p.Format(l))
+	}
 	cnf := logic.CNF(l)
 	s := formatSAT(cnf)
 	fmt.Printf("SAT:\n%s\n", s)
@@ -49,6 +131,33 @@ func checkSanity(stoichio StoichioMatrix, irreversible []bool) {
 	}
 }
 
+// Reads SBML if the name of the input ends in .xml or .sbml. The
+// reactions and metabolites of the matrix format are named by their
+// 1-based indices.
+func readNetwork() *stoichio.Network {
+	if strings.HasSuffix(*input, ".xml") || strings.HasSuffix(*input, ".sbml") {
+		n, e := stoichio.ReadNetwork(*input)
+		if e != nil {
+			panic("Could not read network: " + e.Error())
+		}
+		checkSanity(StoichioMatrix(n.Matrix), n.Irreversible())
+		return n
+	}
+	matrixstring, irreversiblestring := ReadInputStrings()
+	m, scale := ParseMatrix(matrixstring)
+	irreversible := ParseIrreversible(irreversiblestring)
+	checkSanity(m, irreversible)
+	n := stoichio.NewNetwork(stoichio.Matrix(m), irreversible)
+	n.Scale = scale
+	for i := range n.Metabolites {
+		n.Metabolites[i].ID = strconv.Itoa(i + 1)
+	}
+	for j := range n.Reactions {
+		n.Reactions[j].ID = strconv.Itoa(j + 1)
+	}
+	return n
+}
+
 func ReadInputStrings() (string, string) {
 	var r *bufio.Reader
 
@@ -79,25 +188,16 @@ func ReadInputStrings() (string, string) {
 	return string(matrixstring), string(irreversiblestring)
 }
 
-type Cell int8
-type StoichioMatrix [][]Cell
-
-func ParseMatrix(ms string) StoichioMatrix {
-	ms = cleanString(ms)
-	rowstrings := strings.Split(ms, ";")
-	r := make([][]Cell, len(rowstrings))
-	for i, rowstring := range rowstrings {
-		cellstrings := strings.Fields(rowstring)
-		r[i] = make([]Cell, len(cellstrings))
-		for j, cellstring := range cellstrings {
-			v, e := strconv.ParseInt(cellstring, 0, 64)
-			if e != nil {
-				panic("Invalid cell value: " + cellstring)
-			}
-			r[i][j] = Cell(v)
-		}
+type StoichioMatrix [][]stoichio.Cell
+
+// Also returns the factors by which columns with fractions were
+// scaled to integers.
+func ParseMatrix(ms string) (StoichioMatrix, []*big.Int) {
+	m, scale, e := stoichio.ParseMatrix(ms)
+	if e != nil {
+		panic("Invalid matrix: " + e.Error())
 	}
-	return StoichioMatrix(r)
+	return StoichioMatrix(m), scale
 }
 
 func cleanString(ms string) string {
@@ -134,36 +234,48 @@ func ParseIrreversible(is string) []bool {
 	return r
 }
 
-func generateLogic(stoichio StoichioMatrix, irreversible []bool) logic.Node {
+// The variable of reaction j is names[j], those of the directions of
+// reversible reactions get "f" and "b" appended.
+func generateLogic(m StoichioMatrix, irreversible []bool, names []string) logic.Node {
+	sparse, e := stoichio.NewSparse(stoichio.Matrix(m))
+	if e != nil {
+		panic("Invalid matrix: " + e.Error())
+	}
 	reversiblemap := map[string]string{}
+	for reactionidx, isIrreversible := range irreversible {
+		if !isIrreversible {
+			reactionname := names[reactionidx]
+			if *sat {
+				idx := len(irreversible) + len(reversiblemap) + 1
+				reversiblemap[reactionname+"f"] = strconv.Itoa(idx)
+				reversiblemap[reactionname+"b"] = strconv.Itoa(idx + 1)
+			} else {
+				reversiblemap[reactionname+"f"] = reactionname + "f"
+				reversiblemap[reactionname+"b"] = reactionname + "b"
+			}
+		}
+	}
 	root := logic.NewOperation(logic.AND)
-	for _, metabol := range stoichio {
+	touched := make([]bool, len(irreversible))
+	for metabol := 0; metabol < sparse.NumRows(); metabol++ {
 		metaboliteins, metaboliteouts := logic.NewOperation(logic.OR), logic.NewOperation(logic.OR)
-		// Traverse metabolites
-		for reactionidx, reaction := range metabol {
-			reactionname := strconv.Itoa(reactionidx + 1)
+		// Traverse the reactions of the metabolite
+		reactions, coefficients := sparse.Row(metabol)
+		for k, reactionidx := range reactions {
+			reactionname := names[reactionidx]
+			touched[reactionidx] = true
 			if !irreversible[reactionidx] {
-				if _, ok := reversiblemap[reactionname+"f"]; !ok {
-					if *sat {
-						idx := len(irreversible) + len(reversiblemap) + 1
-						reversiblemap[reactionname+"f"] = strconv.Itoa(idx)
-						reversiblemap[reactionname+"b"] = strconv.Itoa(idx + 1)
-					} else {
-						reversiblemap[reactionname+"f"] = reactionname + "f"
-						reversiblemap[reactionname+"b"] = reactionname + "b"
-					}
-				}
-				if reaction > 0 {
+				if coefficients[k] > 0 {
 					metaboliteins.PushOperands(logic.NewLeaf(reversiblemap[reactionname+"f"]))
 					metaboliteouts.PushOperands(logic.NewLeaf(reversiblemap[reactionname+"b"]))
-				} else if reaction < 0 {
+				} else {
 					metaboliteins.PushOperands(logic.NewLeaf(reversiblemap[reactionname+"b"]))
 					metaboliteouts.PushOperands(logic.NewLeaf(reversiblemap[reactionname+"f"]))
 				}
 			} else {
-				if reaction > 0 {
+				if coefficients[k] > 0 {
 					metaboliteins.PushOperands(logic.NewLeaf(reactionname))
-				} else if reaction < 0 {
+				} else {
 					metaboliteouts.PushOperands(logic.NewLeaf(reactionname))
 				}
 			}
@@ -174,19 +286,15 @@ func generateLogic(stoichio StoichioMatrix, irreversible []bool) logic.Node {
 	// Traverse irreversible reactions
 	for reactionidx, isIrreversible := range irreversible {
 		if isIrreversible {
-			continue
-		}
-		varname := strconv.Itoa(reactionidx + 1)
-		if _, ok := reversiblemap[varname+"f"]; !ok {
-			if *sat {
-				idx := len(irreversible) + len(reversiblemap) + 1
-				reversiblemap[varname+"f"] = strconv.Itoa(idx)
-				reversiblemap[varname+"b"] = strconv.Itoa(idx + 1)
-			} else {
-				reversiblemap[varname+"f"] = varname + "f"
-				reversiblemap[varname+"b"] = varname + "b"
+			// Reactions no metabolite touches, like lumped pathways
+			// after -compress, are free, but still need a variable.
+			if !touched[reactionidx] {
+				reaction := logic.NewLeaf(names[reactionidx])
+				root.PushOperands(logic.NewOperation(logic.OR, reaction, logic.NewOperation(logic.NOT, reaction)))
 			}
+			continue
 		}
+		varname := names[reactionidx]
 		in := logic.NewLeaf(reversiblemap[varname+"f"])
 		out := logic.NewLeaf(reversiblemap[varname+"b"])
 		reaction := logic.NewLeaf(varname)
@@ -198,6 +306,284 @@ func generateLogic(stoichio StoichioMatrix, irreversible []bool) logic.Node {
 	return root
 }
 
+// Returns nil unless -compress is given.
+func compressNetwork(m StoichioMatrix, irreversible []bool) *stoichio.Compression {
+	if !*compress {
+		return nil
+	}
+	if !*efm && *jobs == 0 && !*backbone || *fca || *reaction != 0 || *avail != "" {
+		panic("-compress only works with -efm, -j and -b")
+	}
+	c, e := stoichio.Compress(stoichio.Matrix(m), irreversible)
+	if e != nil {
+		panic("Could not compress network: " + e.Error())
+	}
+	fmt.Fprintf(os.Stderr, "Compressed to %d metabolites and %d reactions, %d reactions blocked\n", len(c.Matrix), len(c.Irreversible), len(c.Blocked))
+	return c
+}
+
+// Names the variables of the reactions by their IDs. SAT output needs
+// numbers, and the reactions of a compressed network have no IDs, so
+// these are named by their 1-based indices.
+func leafNames(n *stoichio.Network, c *stoichio.Compression) []string {
+	if *sat || c != nil {
+		count := len(n.Reactions)
+		if c != nil {
+			count = len(c.Irreversible)
+		}
+		r := make([]string, count)
+		for j := range r {
+			r[j] = strconv.Itoa(j + 1)
+		}
+		return r
+	}
+	r := make([]string, len(n.Reactions))
+	for j, reaction := range n.Reactions {
+		r[j] = reaction.ID
+	}
+	return r
+}
+
+// Returns the labels of the reactions, mapped back to the original
+// reactions if c is not nil.
+func reactionNames(n *stoichio.Network, reactions []bool, c *stoichio.Compression) []string {
+	if c != nil {
+		reactions = c.ExpandSupport(reactions)
+	}
+	r := make([]string, 0)
+	for j, ok := range reactions {
+		if ok {
+			r = append(r, n.ReactionLabel(j))
+		}
+	}
+	return r
+}
+
+func solve(n *stoichio.Network, leafs []string, l logic.Node, jobs int, c *stoichio.Compression) {
+	cs := logic.Clauses(l)
+	fragment := logic.DetectFragment(cs)
+	fmt.Printf("Fragment: %s\n", fragment)
+	var r logic.PortfolioResult
+	if fragment != logic.General {
+		r.Model, r.Satisfiable, _ = logic.SolveFragment(cs)
+	} else {
+		var e error
+		r, e = logic.SolvePortfolio(context.Background(), cs, logic.DefaultPortfolio(jobs))
+		if e != nil {
+			panic("Parallel solvers failed: " + e.Error())
+		}
+	}
+	if !r.Satisfiable {
+		fmt.Println("Unsatisfiable")
+		return
+	}
+	active := make([]bool, len(leafs))
+	for j := range active {
+		if idx, ok := cs.Index[leafs[j]]; ok && r.Model[idx] {
+			active[j] = true
+		}
+	}
+	fmt.Printf("Active reactions: %s\n", strings.Join(reactionNames(n, active, c), ", "))
+}
+
+// Modes are printed with 1-based reactions like the SAT output, so
+// that solutionfilter can compare them with its solutions. Fluxes are
+// printed in the units of the input, not of the scaled matrix.
+func printModes(m StoichioMatrix, irreversible []bool, c *stoichio.Compression, scale []*big.Int) {
+	modes, e := stoichio.ElementaryModes(stoichio.Matrix(m), irreversible, stoichio.EFMOptions{RankTest: *ranktest})
+	if e != nil {
+		panic("Could not enumerate elementary modes: " + e.Error())
+	}
+	for _, f := range modes {
+		if c != nil {
+			f = c.ExpandMode(f)
+		}
+		if *fluxes {
+			fmt.Println(f.Unscale(scale))
+		} else {
+			fmt.Println(f.SupportString())
+		}
+	}
+}
+
+func printFBA(n *stoichio.Network, objective int) {
+	lower, upper := stoichio.DefaultBounds(n.Irreversible(), *bound)
+	r, e := stoichio.FBA(n.Matrix, n.Scale, lower, upper, objective)
+	if e != nil {
+		panic("Flux balance analysis failed: " + e.Error())
+	}
+	fmt.Printf("Objective: %g\n", r.Objective)
+	fmt.Printf("Reaction %12s %12s\n", "flux", "reduced cost")
+	for j, v := range r.Flux {
+		fmt.Printf("%8s %12g %12g\n", n.ReactionLabel(j), v, r.ReducedCosts[j])
+	}
+	fmt.Printf("Metabolite %10s\n", "shadow price")
+	for i, y := range r.Duals {
+		fmt.Printf("%10s %12g\n", n.MetaboliteLabel(i), y)
+	}
+}
+
+// Without -fba, objective is -1 and the ranges are not restricted by
+// an objective.
+func printFVA(n *stoichio.Network, objective int) {
+	lower, upper := stoichio.DefaultBounds(n.Irreversible(), *bound)
+	r, e := stoichio.FVA(n.Matrix, n.Scale, lower, upper, stoichio.FVAOptions{
+		Objective: objective,
+		Fraction:  *fraction,
+		Workers:   *workers,
+	})
+	if e != nil {
+		panic("Flux variability analysis failed: " + e.Error())
+	}
+	if objective >= 0 {
+		fmt.Printf("Objective: %g\n", r.Optimum)
+	}
+	names := make([]string, len(n.Reactions))
+	for j := range names {
+		names[j] = n.ReactionLabel(j)
+	}
+	r.Write(os.Stdout, names)
+}
+
+// Couplings j -> k are confirmed if the formula has no model with j
+// active and k inactive. Since the formula only holds necessary
+// conditions for a flux, the SAT encoding may miss couplings which
+// are due to the stoichiometry, but never finds wrong ones.
+func printCoupling(network *stoichio.Network, leafs []string, l logic.Node) {
+	a, e := stoichio.FluxCoupling(network.Matrix, network.Irreversible(), stoichio.EFMOptions{RankTest: *ranktest})
+	if e != nil {
+		panic("Could not compute flux coupling: " + e.Error())
+	}
+	n := len(network.Reactions)
+	fmt.Printf("Coupling:\n")
+	for j, row := range a.Matrix {
+		cells := make([]string, n)
+		for k, c := range row {
+			cells[k] = fmt.Sprintf("%7s", c)
+		}
+		fmt.Printf("%4s %s\n", network.ReactionLabel(j), strings.Join(cells, " "))
+	}
+	groups := make([]string, len(a.Groups))
+	for i, g := range a.Groups {
+		names := make([]string, len(g))
+		for k, j := range g {
+			names[k] = network.ReactionLabel(j)
+		}
+		groups[i] = strings.Join(names, " ")
+	}
+	fmt.Printf("Coupled groups: %s\n", strings.Join(groups, "; "))
+
+	cs := logic.Clauses(l)
+	lits := make([]logic.Lit, n)
+	for j := range lits {
+		lits[j] = logic.Lit(cs.Var(leafs[j]))
+	}
+	s := logic.NewSolverFromClauses(cs)
+	total, confirmed := 0, 0
+	missed := make([]string, 0)
+	for j := 0; j < n; j++ {
+		if a.Blocked[j] {
+			total++
+			if !s.Solve(lits[j]) {
+				confirmed++
+			} else {
+				missed = append(missed, network.ReactionLabel(j)+" blocked")
+			}
+			continue
+		}
+		for k := 0; k < n; k++ {
+			c := a.Matrix[j][k]
+			if j == k || c == stoichio.Uncoupled || c == stoichio.ReverseCoupled {
+				continue
+			}
+			total++
+			if !s.Solve(lits[j], lits[k].Neg()) {
+				confirmed++
+			} else {
+				missed = append(missed, network.ReactionLabel(j)+" -> "+network.ReactionLabel(k))
+			}
+		}
+	}
+	fmt.Printf("Confirmed by SAT: %d of %d\n", confirmed, total)
+	if len(missed) > 0 {
+		fmt.Printf("Not confirmed: %s\n", strings.Join(missed, ", "))
+	}
+}
+
+func printBackbone(n *stoichio.Network, leafs []string, l logic.Node, c *stoichio.Compression) {
+	cs := logic.Clauses(l)
+	backbone, ok := logic.Backbone(cs)
+	if !ok {
+		fmt.Println("Unsatisfiable")
+		return
+	}
+	fixed := make(map[logic.Lit]bool)
+	for _, lit := range backbone {
+		fixed[lit] = true
+	}
+	essential, blocked := make([]bool, len(leafs)), make([]bool, len(leafs))
+	for j, name := range leafs {
+		essential[j] = fixed[cs.Lit(name, true)]
+		blocked[j] = fixed[cs.Lit(name, false)]
+	}
+	if c != nil {
+		essential, blocked = c.ExpandSupport(essential), c.ExpandSupport(blocked)
+		for _, j := range c.Blocked {
+			blocked[j] = true
+		}
+	}
+	fmt.Printf("Essential reactions: %s\n", strings.Join(reactionNames(n, essential, nil), ", "))
+	fmt.Printf("Blocked reactions: %s\n", strings.Join(reactionNames(n, blocked, nil), ", "))
+}
+
+// After compression blocked every reaction, the empty flux is the
+// only one and no formula is needed. Only -b and -j get here.
+func printAllBlocked(n *stoichio.Network, c *stoichio.Compression) {
+	if !*backbone {
+		fmt.Println("Active reactions: ")
+		return
+	}
+	blocked := make([]string, len(c.Blocked))
+	for k, j := range c.Blocked {
+		blocked[k] = n.ReactionLabel(j)
+	}
+	fmt.Println("Essential reactions: ")
+	fmt.Printf("Blocked reactions: %s\n", strings.Join(blocked, ", "))
+}
+
+// Every listed reaction gets a variable a<j> which is true with the
+// given probability and required for the reaction. Only meaningful
+// with -r, since the empty flux mode always exists.
+func availability(l logic.Node, file string, leafs []string) {
+	f, e := os.Open(file)
+	if e != nil {
+		panic("Could not open availability file")
+	}
+	probs, e := logic.ReadProbabilities(f)
+	f.Close()
+	if e != nil {
+		panic("Could not read availability file: " + e.Error())
+	}
+	m := logic.NewOperation(logic.AND, l)
+	for name := range probs {
+		j, e := strconv.Atoi(name)
+		if e != nil || j < 1 || j > len(leafs) {
+			panic("Invalid reaction in availability file: " + name)
+		}
+		m.PushOperands(logic.NewOperation(logic.IF, logic.NewLeaf(leafs[j-1]), logic.NewLeaf("a"+name)))
+	}
+	cs := logic.Clauses(m)
+	w := make(logic.Weights)
+	projection := make([]int, 0, len(probs))
+	for name, p := range probs {
+		v := cs.Var("a" + name)
+		w.SetProbability(v, p)
+		projection = append(projection, v)
+	}
+	r := logic.WeightedCount(cs, w, projection)
+	fmt.Printf("Probability: %s (%s)\n", r.RatString(), r.FloatString(10))
+}
+
 func formatSAT(n logic.Node) string {
 	s := ""
 	if _, ok := n.(logic.Leaf); ok {
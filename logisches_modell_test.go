@@ -0,0 +1,88 @@
+package main
+
+import (
+	"./logic"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Runs main on the given input and returns what it prints.
+func run(t *testing.T, input string, args ...string) string {
+	dir, e := ioutil.TempDir("", "logisches_modell")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "input")
+	if e := ioutil.WriteFile(file, []byte(input), 0644); e != nil {
+		t.Fatal(e)
+	}
+	flag.VisitAll(func(f *flag.Flag) {
+		if !strings.HasPrefix(f.Name, "test.") {
+			f.Value.Set(f.DefValue)
+		}
+	})
+	os.Args = append([]string{"logisches_modell", "-i", file}, args...)
+
+	stdout, stderr := os.Stdout, os.Stderr
+	r, w, e := os.Pipe()
+	if e != nil {
+		t.Fatal(e)
+	}
+	null, e := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer null.Close()
+	os.Stdout, os.Stderr = w, null
+	defer func() {
+		os.Stdout, os.Stderr = stdout, stderr
+	}()
+	done := make(chan string)
+	go func() {
+		b, _ := ioutil.ReadAll(r)
+		done <- string(b)
+	}()
+	main()
+	w.Close()
+	return <-done
+}
+
+func TestCompressLumpedPathway(t *testing.T) {
+	for _, x := range []struct {
+		input, backbone string
+	}{
+		{"[1 -1]\n1 1\n", "Essential reactions: \nBlocked reactions: \n"},
+		// The dead end blocks reaction 3, the others form one pathway
+		{"[1 -1 0 0; 0 1 -1 -1; 0 0 1 0]\n1 1 1 1\n", "Essential reactions: \nBlocked reactions: 3\n"},
+		// Nothing is left to build a formula of
+		{"[1 0; 0 1]\n1 1\n", "Essential reactions: \nBlocked reactions: 1, 2\n"},
+	} {
+		if s := run(t, x.input, "-compress", "-b"); s != x.backbone {
+			t.Fatalf("Backbone of %q is %q, expected %q", x.input, s, x.backbone)
+		}
+		if s := run(t, x.input, "-compress", "-j", "1"); !strings.Contains(s, "Active reactions:") {
+			t.Fatalf("Solving %q printed %q", x.input, s)
+		}
+	}
+	// Without rows the lumped reaction still needs a variable
+	cs := logic.Clauses(generateLogic(StoichioMatrix{}, []bool{true}, []string{"1"}))
+	if _, ok := cs.Index["1"]; !ok {
+		t.Fatalf("No variable for the lumped reaction in %v", cs.Index)
+	}
+}
+
+func TestSBMLLabels(t *testing.T) {
+	// The later -i overrides the empty input of run
+	s := run(t, "", "-i", "stoichio/testdata/toy.xml", "-b")
+	if s != "Essential reactions: \nBlocked reactions: A uptake, A to B, R_back, R_o2\n" {
+		t.Fatalf("Backbone %q", s)
+	}
+	if s := run(t, "", "-i", "stoichio/testdata/toy.xml", "-fva"); !strings.Contains(s, "A to B") {
+		t.Fatalf("Ranges %q", s)
+	}
+}
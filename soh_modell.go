@@ -3,31 +3,62 @@ package main
 import (
 	"./logic"
 	"./stoichio"
+	"bufio"
+	"context"
 	"fmt"
 	"github.com/voxelbrain/goptions"
+	"io/ioutil"
 	"log"
+	"math/big"
 	"os"
 	"sort"
-	"strconv"
 	"strings"
 	"text/tabwriter"
 )
 
-const METABOL = "m_%d_t=%d"
-const REACTION = "r_%d_t=%d"
+const METABOL = "m_%s_t=%d"
+const REACTION = "r_%s_t=%d"
+const AVAILABLE = "a_%s"
 
 const VERSION = "0.1"
 
 func main() {
 	options := struct {
-		InputFile     string `goptions:"-i, --input, description='File to read', obligatory"`
-		TimeLimit     int    `goptions:"-t, --time, description='Maximum number of timesteps (default: 10)'"`
-		Targetset     string `goptions:"-z, --targetset, description='Comma-separated list of metabolite indices'"`
-		Verbosity     []bool `goptions:"-v, --verbose, description='Increase verbosity'"`
-		SAT           bool   `goptions:"-s, --output-sat, description='Output in SAT format instead of human-readable CNF'"`
+		InputFile     string  `goptions:"-i, --input, description='File to read (SBML if the name ends in .xml or .sbml)', obligatory"`
+		TimeLimit     int     `goptions:"-t, --time, description='Maximum number of timesteps (default: 10)'"`
+		Targetset     string  `goptions:"-z, --targetset, description='Comma-separated list of metabolite IDs, names or indices'"`
+		Verbosity     []bool  `goptions:"-v, --verbose, description='Increase verbosity'"`
+		SAT           bool    `goptions:"-s, --output-sat, description='Output in SAT format instead of human-readable CNF'"`
+		Backbone      bool    `goptions:"-b, --backbone, description='List essential and blocked reactions'"`
+		Solve         bool    `goptions:"-S, --solve, description='Solve with the built-in solver'"`
+		ProofFile     string  `goptions:"-p, --proof, description='Write a DRAT proof to this file if unsatisfiable (implies --solve)'"`
+		CheckProof    string  `goptions:"-c, --check-proof, description='Check a DRAT proof (LRAT if the name ends in .lrat) against the generated CNF'"`
+		Jobs          int     `goptions:"-j, --jobs, description='Number of solvers to run in parallel for --solve (default: 1)'"`
+		Cubes         int     `goptions:"--cubes, description='Split the CNF into this many cubes and solve them one after another'"`
+		CubeOutput    string  `goptions:"--cube-output, description='Write the cubes instead of solving them (iCNF if the name ends in .icnf, else <name>_<i>.cnf)'"`
+		MergeCubes    string  `goptions:"--merge-cubes, description='Merge external results for --cube-output files from <name>_<i>.out and <name>_<i>.drat'"`
+		LocalSearch   bool    `goptions:"-l, --local-search, description='Look for a model with probSAT (WalkSAT with --walksat)'"`
+		WalkSAT       bool    `goptions:"--walksat, description='Use WalkSAT for --local-search'"`
+		Flips         int     `goptions:"--flips, description='Flips per try for --local-search'"`
+		Tries         int     `goptions:"--tries, description='Tries for --local-search (default: 10)'"`
+		Noise         float64 `goptions:"--noise, description='Noise parameter for --local-search'"`
+		Seed          int64   `goptions:"--seed, description='Seed for --local-search'"`
+		Compile       string  `goptions:"--compile, description='Compile the CNF to d-DNNF, write it to this file and count its models'"`
+		Query         string  `goptions:"--query, description='Count models and find a minimal model of a d-DNNF written by --compile'"`
+		Knockout      string  `goptions:"--knockout, description='Comma-separated list of reaction indices to disable for --query'"`
+		Availability  string  `goptions:"--availability, description='File of reaction indices and probabilities; print the probability that the target set is producible'"`
+		Save          string  `goptions:"--save, description='Save the generated formula to this file (JSON if the name ends in .json, else binary)'"`
+		Load          string  `goptions:"--load, description='Load the formula from a file written by --save instead of generating it'"`
+		MaxClauses    int64   `goptions:"--max-clauses, description='Refuse to convert formulas whose CNF has more clauses (default: 10000000)'"`
+		Force         bool    `goptions:"-f, --force, description='Convert to CNF regardless of --max-clauses'"`
+		Notation      string  `goptions:"--notation, description='Print formulas as ascii (default), unicode or latex'"`
+		Width         int     `goptions:"--width, description='Break formulas printed with --notation into lines of this width'"`
 		goptions.Help `goptions:"-h, --help, description='Show this help'"`
 	}{
-		TimeLimit: 10,
+		TimeLimit:  10,
+		Jobs:       1,
+		Tries:      10,
+		MaxClauses: 10000000,
 	}
 
 	err := goptions.Parse(&options)
@@ -37,6 +68,11 @@ func main() {
 		return
 	}
 
+	format, err := formatter(options.Notation, options.Width)
+	if err != nil {
+		log.Fatalf("%s", err)
+	}
+
 	z := []string{}
 	if len(options.Targetset) > 0 {
 		z = strings.Split(options.Targetset, ",")
@@ -45,24 +81,37 @@ func main() {
 		}
 	}
 
-	matrix, irreversible, err := stoichio.ReadFile(options.InputFile)
+	network, err := stoichio.ReadNetwork(options.InputFile)
 	if err != nil {
 		log.Fatalf("Could not read file: %s", err)
 	}
+	matrix, irreversible := network.Matrix, network.Irreversible()
+	reversed := make([]string, 0)
+	for _, r := range network.Reactions {
+		if r.Reversed {
+			reversed = append(reversed, r.ID)
+		}
+	}
+	if len(reversed) > 0 {
+		log.Printf("Reactions turned around to run forwards: %s", strings.Join(reversed, ", "))
+	}
+	sparse, err := stoichio.NewSparse(matrix)
+	if err != nil {
+		log.Fatalf("Invalid matrix: %s", err)
+	}
 
 	t_in, t_out := make([]int, 0), make([]int, 0)
 	sourceset := make([]int, 0)
-	for i := 0; i < matrix.NumCols(); i++ {
-		col := matrix.Col(i)
-		if len(col.Supp()) != 1 {
+	for i := 0; i < sparse.NumCols(); i++ {
+		supp, col := sparse.Col(i)
+		if len(supp) != 1 {
 			continue
 		}
-		supp := col.Supp()
-		if col[supp[0]] > 0 || (col[supp[0]] < 0 && !irreversible[i]) {
+		if col[0] > 0 || (col[0] < 0 && !irreversible[i]) {
 			t_in = append(t_in, i)
 			sourceset = append(sourceset, supp[0])
 		}
-		if col[supp[0]] < 0 || (col[supp[0]] > 0 && !irreversible[i]) {
+		if col[0] < 0 || (col[0] > 0 && !irreversible[i]) {
 			t_out = append(t_out, i)
 		}
 	}
@@ -73,37 +122,104 @@ func main() {
 		log.Printf("z: %#v", z)
 	}
 
-	a1 := logic.NewOperation(logic.AND)
-	for i := 0; i < matrix.NumRows(); i++ {
-		if contains(sourceset, i) {
-			a1.PushOperands(logic.NewLeaf(fmt.Sprintf(METABOL, i, 0)))
-		} else {
-			a1.PushOperands(logic.NewOperation(logic.NOT, logic.NewLeaf(fmt.Sprintf(METABOL, i, 0))))
-		}
-	}
-
-	a2 := logic.NewOperation(logic.AND)
-	a3 := logic.NewOperation(logic.AND)
-	a4 := logic.NewOperation(logic.AND)
-	a5 := logic.NewOperation(logic.AND)
-	a6 := logic.NewOperation(logic.AND)
-	a7 := logic.NewOperation(logic.AND)
-	a5.PushOperands(generateA5(0, matrix))
-	a6.PushOperands(generateA6(0, matrix))
-	for t := 1; t < options.TimeLimit; t++ {
-		a2.PushOperands(generateA2(t, matrix, irreversible))
-		a3.PushOperands(generateA3(t, matrix, irreversible))
-		a4.PushOperands(generateA4(t, matrix, irreversible, sourceset))
-		a5.PushOperands(generateA5(t, matrix))
-		a6.PushOperands(generateA6(t, matrix))
-	}
-	a2.PushOperands(generateA2(options.TimeLimit, matrix, irreversible))
-	a3.PushOperands(generateA3(options.TimeLimit, matrix, irreversible))
-	a4.PushOperands(generateA4(options.TimeLimit, matrix, irreversible, sourceset))
-	a7.PushOperands(generateA7(options.TimeLimit, z)...)
-	a := logic.NewOperation(logic.AND, a1, a2, a3, a4, a5, a6)
-	if len(z) > 0 {
-		a.PushOperands(a7)
+	var a logic.Node
+	var a1, a2, a3, a4, a5, a6, a7 *logic.Operation
+	if options.Load != "" {
+		a, err = logic.LoadFormula(options.Load)
+		if err != nil {
+			log.Fatalf("Could not load formula: %s", err)
+		}
+	} else {
+		a1 = logic.NewOperation(logic.AND)
+		for i := 0; i < matrix.NumRows(); i++ {
+			if contains(sourceset, i) {
+				a1.PushOperands(logic.NewLeaf(fmt.Sprintf(METABOL, network.Metabolites[i].ID, 0)))
+			} else {
+				a1.PushOperands(logic.NewOperation(logic.NOT, logic.NewLeaf(fmt.Sprintf(METABOL, network.Metabolites[i].ID, 0))))
+			}
+		}
+
+		a2 = logic.NewOperation(logic.AND)
+		a3 = logic.NewOperation(logic.AND)
+		a4 = logic.NewOperation(logic.AND)
+		a5 = logic.NewOperation(logic.AND)
+		a6 = logic.NewOperation(logic.AND)
+		a7 = logic.NewOperation(logic.AND)
+		a5.PushOperands(generateA5(0, network))
+		a6.PushOperands(generateA6(0, network))
+		for t := 1; t < options.TimeLimit; t++ {
+			a2.PushOperands(generateA2(t, network, sparse, irreversible))
+			a3.PushOperands(generateA3(t, network, sparse, irreversible))
+			a4.PushOperands(generateA4(t, network, sparse, irreversible, sourceset))
+			a5.PushOperands(generateA5(t, network))
+			a6.PushOperands(generateA6(t, network))
+		}
+		a2.PushOperands(generateA2(options.TimeLimit, network, sparse, irreversible))
+		a3.PushOperands(generateA3(options.TimeLimit, network, sparse, irreversible))
+		a4.PushOperands(generateA4(options.TimeLimit, network, sparse, irreversible, sourceset))
+		a7.PushOperands(generateA7(options.TimeLimit, network, z)...)
+		root := logic.NewOperation(logic.AND, a1, a2, a3, a4, a5, a6)
+		if len(z) > 0 {
+			root.PushOperands(a7)
+		}
+		a = root
+	}
+	if options.Save != "" {
+		if err := logic.SaveFormula(options.Save, a); err != nil {
+			log.Fatalf("Could not save formula: %s", err)
+		}
+	}
+
+	checkFeasible(a, options.MaxClauses, options.Force)
+
+	if options.Backbone {
+		printBackbone(a, network, options.TimeLimit)
+		return
+	}
+
+	if options.LocalSearch {
+		opts := logic.LocalSearchOptions{
+			Algorithm: logic.ProbSAT,
+			Seed:      options.Seed,
+			Noise:     options.Noise,
+			MaxFlips:  options.Flips,
+			MaxTries:  options.Tries,
+		}
+		if options.WalkSAT {
+			opts.Algorithm = logic.WalkSAT
+		}
+		localSearch(a, opts)
+		return
+	}
+
+	if options.Cubes > 0 {
+		cubeAndConquer(a, options.Cubes, options.CubeOutput, options.MergeCubes, options.ProofFile)
+		return
+	}
+
+	if options.Solve || options.ProofFile != "" {
+		solve(a, options.ProofFile, options.Jobs)
+		return
+	}
+
+	if options.CheckProof != "" {
+		checkProof(a, options.CheckProof)
+		return
+	}
+
+	if options.Compile != "" {
+		compile(a, options.Compile)
+		return
+	}
+
+	if options.Query != "" {
+		query(a, network, options.Query, options.Knockout, options.TimeLimit)
+		return
+	}
+
+	if options.Availability != "" {
+		availability(a, network, options.Availability, options.TimeLimit)
+		return
 	}
 
 	if options.SAT {
@@ -119,110 +235,450 @@ func main() {
 		fmt.Println("SAT:")
 		fmt.Println(sat)
 	} else {
-		if len(options.Verbosity) >= 1 {
-			log.Printf("A1:\n%s", a1)
-			log.Printf("A2:\n%s", a2)
-			log.Printf("A3:\n%s", a3)
-			log.Printf("A4:\n%s", a4)
-			log.Printf("A5:\n%s", a5)
-			log.Printf("A6:\n%s", a6)
-			log.Printf("A7:\n%s", a7)
-			log.Printf("A:\n%s", a)
+		if len(options.Verbosity) >= 1 && a1 != nil {
+			log.Printf("A1:\n%s", format(a1))
+			log.Printf("A2:\n%s", format(a2))
+			log.Printf("A3:\n%s", format(a3))
+			log.Printf("A4:\n%s", format(a4))
+			log.Printf("A5:\n%s", format(a5))
+			log.Printf("A6:\n%s", format(a6))
+			log.Printf("A7:\n%s", format(a7))
+			log.Printf("A:\n%s", format(a))
 		}
-		log.Printf("CNF(A):\n%s", logic.CNF(a))
+		log.Printf("CNF(A):\n%s", format(logic.CNF(a)))
+	}
+
+}
+
+// Returns a function printing formulas in the given notation,
+// ascii being the prefix form of String.
+func formatter(notation string, width int) (func(logic.Node) string, error) {
+	if notation == "" || notation == "ascii" {
+		return logic.Node.String, nil
+	}
+	n, ok := logic.Notations[notation]
+	if !ok {
+		return nil, fmt.Errorf("Unknown notation %q", notation)
 	}
+	p := &logic.Printer{Notation: n, Width: width}
+	return p.Format, nil
+}
 
+// Prints the statistics of a and stops if its CNF would be too
+// large, unless forced.
+func checkFeasible(a logic.Node, maxClauses int64, force bool) {
+	stats := logic.Analyze(a)
+	log.Printf("Formula:\n%s", stats)
+	if !force && stats.CNFClauses.Cmp(big.NewInt(maxClauses)) > 0 {
+		log.Fatalf("CNF would have %s clauses, more than %d. Use --force to convert anyway", stats.CNFClauses, maxClauses)
+	}
 }
 
-func generateA2(t int, matrix stoichio.Matrix, irreversible []bool) logic.Node {
+func generateA2(t int, network *stoichio.Network, sparse *stoichio.Sparse, irreversible []bool) logic.Node {
 	m := logic.NewOperation(logic.AND)
-	for j := 0; j < matrix.NumCols(); j++ {
+	for j := 0; j < sparse.NumCols(); j++ {
 		alpha := logic.NewOperation(logic.AND)
-		for i := 0; i < matrix.NumRows(); i++ {
-			if matrix[i][j] < 0 || (matrix[i][j] > 0 && !irreversible[j]) {
-				alpha.PushOperands(logic.NewLeaf(fmt.Sprintf(METABOL, i, t-1)))
+		rows, col := sparse.Col(j)
+		for k, i := range rows {
+			if col[k] < 0 || (col[k] > 0 && !irreversible[j]) {
+				alpha.PushOperands(logic.NewLeaf(fmt.Sprintf(METABOL, network.Metabolites[i].ID, t-1)))
 			}
 		}
 		if len(alpha.Operands) > 0 {
 			m.PushOperands(logic.NewOperation(logic.IF,
-				logic.NewLeaf(fmt.Sprintf(REACTION, j, t)),
+				logic.NewLeaf(fmt.Sprintf(REACTION, network.Reactions[j].ID, t)),
 				alpha))
 		}
 	}
 	return m
 }
 
-func generateA3(t int, matrix stoichio.Matrix, irreversible []bool) logic.Node {
+func generateA3(t int, network *stoichio.Network, sparse *stoichio.Sparse, irreversible []bool) logic.Node {
 	m := logic.NewOperation(logic.AND)
-	for j := 0; j < matrix.NumCols(); j++ {
+	for j := 0; j < sparse.NumCols(); j++ {
 		beta := logic.NewOperation(logic.AND)
-		for i := 0; i < matrix.NumRows(); i++ {
-			if matrix[i][j] > 0 || (matrix[i][j] < 0 && !irreversible[j]) {
-				beta.PushOperands(logic.NewLeaf(fmt.Sprintf(METABOL, i, t)))
+		rows, col := sparse.Col(j)
+		for k, i := range rows {
+			if col[k] > 0 || (col[k] < 0 && !irreversible[j]) {
+				beta.PushOperands(logic.NewLeaf(fmt.Sprintf(METABOL, network.Metabolites[i].ID, t)))
 			}
 		}
 		if len(beta.Operands) > 0 {
 			m.PushOperands(logic.NewOperation(logic.IF,
-				logic.NewLeaf(fmt.Sprintf(REACTION, j, t)),
+				logic.NewLeaf(fmt.Sprintf(REACTION, network.Reactions[j].ID, t)),
 				beta))
 		}
 	}
 	return m
 }
 
-func generateA4(t int, matrix stoichio.Matrix, irreversible []bool, sourceset []int) logic.Node {
+func generateA4(t int, network *stoichio.Network, sparse *stoichio.Sparse, irreversible []bool, sourceset []int) logic.Node {
 	m := logic.NewOperation(logic.AND)
-	for i := 0; i < matrix.NumRows(); i++ {
+	for i := 0; i < sparse.NumRows(); i++ {
 		if contains(sourceset, i) {
 			continue
 		}
 		x := logic.NewOperation(logic.OR)
-		x.PushOperands(logic.NewLeaf(fmt.Sprintf(METABOL, i, t-1)))
+		x.PushOperands(logic.NewLeaf(fmt.Sprintf(METABOL, network.Metabolites[i].ID, t-1)))
 
-		for j := 0; j < matrix.NumCols(); j++ {
-			if matrix[i][j] > 0 || (matrix[i][j] < 0 && !irreversible[j]) {
-				x.PushOperands(logic.NewLeaf(fmt.Sprintf(REACTION, j, t)))
+		cols, row := sparse.Row(i)
+		for k, j := range cols {
+			if row[k] > 0 || (row[k] < 0 && !irreversible[j]) {
+				x.PushOperands(logic.NewLeaf(fmt.Sprintf(REACTION, network.Reactions[j].ID, t)))
 			}
 		}
 		m.PushOperands(logic.NewOperation(logic.IF,
-			logic.NewLeaf(fmt.Sprintf(METABOL, i, t)),
+			logic.NewLeaf(fmt.Sprintf(METABOL, network.Metabolites[i].ID, t)),
 			x))
 	}
 	return m
 }
 
-func generateA5(t int, matrix stoichio.Matrix) logic.Node {
+func generateA5(t int, network *stoichio.Network) logic.Node {
 	m := logic.NewOperation(logic.AND)
-	for i := 0; i < matrix.NumRows(); i++ {
+	for i := range network.Metabolites {
 		m.PushOperands(logic.NewOperation(logic.IF,
-			logic.NewLeaf(fmt.Sprintf(METABOL, i, t)),
-			logic.NewLeaf(fmt.Sprintf(METABOL, i, t+1))))
+			logic.NewLeaf(fmt.Sprintf(METABOL, network.Metabolites[i].ID, t)),
+			logic.NewLeaf(fmt.Sprintf(METABOL, network.Metabolites[i].ID, t+1))))
 	}
 	return m
 }
 
-func generateA6(t int, matrix stoichio.Matrix) logic.Node {
+func generateA6(t int, network *stoichio.Network) logic.Node {
 	m := logic.NewOperation(logic.AND)
-	for j := 0; j < matrix.NumCols(); j++ {
+	for j := range network.Reactions {
 		m.PushOperands(logic.NewOperation(logic.IF,
-			logic.NewLeaf(fmt.Sprintf(REACTION, j, t)),
-			logic.NewLeaf(fmt.Sprintf(REACTION, j, t+1))))
+			logic.NewLeaf(fmt.Sprintf(REACTION, network.Reactions[j].ID, t)),
+			logic.NewLeaf(fmt.Sprintf(REACTION, network.Reactions[j].ID, t+1))))
 	}
 	return m
 }
 
-func generateA7(t int, targetset []string) []logic.Node {
+func generateA7(t int, network *stoichio.Network, targetset []string) []logic.Node {
 	m := make([]logic.Node, 0)
-	for _, idx := range targetset {
-		i, e := strconv.ParseInt(idx, 10, 64)
+	for _, name := range targetset {
+		i, e := network.Metabolite(name)
 		if e != nil {
-			log.Fatalf("Invalid integer in target set: %s", idx)
+			log.Fatalf("Invalid target set: %s", e)
 		}
-		m = append(m, logic.NewLeaf(fmt.Sprintf(METABOL, i, t)))
+		m = append(m, logic.NewLeaf(fmt.Sprintf(METABOL, network.Metabolites[i].ID, t)))
 	}
 	return m
 }
 
+func solve(a logic.Node, prooffile string, jobs int) {
+	cs := logic.Clauses(a)
+	fragment := logic.DetectFragment(cs)
+	log.Printf("Fragment: %s", fragment)
+	if fragment != logic.General && prooffile == "" {
+		model, satisfiable, _ := logic.SolveFragment(cs)
+		printModel(cs, satisfiable, model)
+		return
+	}
+	if jobs > 1 {
+		if prooffile != "" {
+			log.Fatalf("Proofs cannot be generated by parallel solvers")
+		}
+		r, err := logic.SolvePortfolio(context.Background(), cs, logic.DefaultPortfolio(jobs))
+		if err != nil {
+			log.Fatalf("Parallel solvers failed: %s", err)
+		}
+		printModel(cs, r.Satisfiable, r.Model)
+		return
+	}
+	s := logic.NewSolver(cs.NumVars)
+	var proof *bufio.Writer
+	if prooffile != "" {
+		f, err := os.Create(prooffile)
+		if err != nil {
+			log.Fatalf("Could not create proof file: %s", err)
+		}
+		defer f.Close()
+		proof = bufio.NewWriter(f)
+		s.Proof = proof
+	}
+	for _, c := range cs.Clauses {
+		s.AddClause(c...)
+	}
+
+	satisfiable := s.Solve()
+	printModel(cs, satisfiable, s.Model())
+	if satisfiable || proof == nil {
+		return
+	}
+	if err := proof.Flush(); err != nil {
+		log.Fatalf("Could not write proof: %s", err)
+	}
+	f, err := os.Open(prooffile)
+	if err != nil {
+		log.Fatalf("Could not reopen proof file: %s", err)
+	}
+	defer f.Close()
+	if err := logic.CheckDRAT(cs, f); err != nil {
+		log.Fatalf("Proof check failed: %s", err)
+	}
+	fmt.Println("Proof verified")
+}
+
+func localSearch(a logic.Node, opts logic.LocalSearchOptions) {
+	cs := logic.Clauses(a)
+	model, ok := logic.LocalSearch(context.Background(), cs, opts)
+	if !ok {
+		fmt.Println("UNKNOWN")
+		return
+	}
+	if !cs.Satisfies(model) {
+		log.Fatalf("Local search returned an invalid model")
+	}
+	printModel(cs, true, model)
+}
+
+func cubeAndConquer(a logic.Node, n int, output, merge, prooffile string) {
+	cs := logic.Clauses(a)
+	split := logic.Cubes(cs, n)
+	log.Printf("%d cubes, %d paths refuted by lookahead", len(split.Cubes), len(split.Refuted))
+
+	if output != "" {
+		if strings.HasSuffix(output, ".icnf") {
+			f, err := os.Create(output)
+			if err != nil {
+				log.Fatalf("Could not create cube file: %s", err)
+			}
+			defer f.Close()
+			if err := logic.WriteICNF(f, cs, split); err != nil {
+				log.Fatalf("Could not write cube file: %s", err)
+			}
+			return
+		}
+		for i, cube := range split.Cubes {
+			name := fmt.Sprintf("%s_%d.cnf", output, i)
+			if err := ioutil.WriteFile(name, []byte(logic.CubeDIMACS(cs, cube)), 0644); err != nil {
+				log.Fatalf("Could not write cube file: %s", err)
+			}
+		}
+		return
+	}
+
+	results := make([]logic.CubeResult, len(split.Cubes))
+	for i, cube := range split.Cubes {
+		if merge == "" {
+			results[i] = logic.SolveCube(cs, cube, prooffile != "")
+			continue
+		}
+		f, err := os.Open(fmt.Sprintf("%s_%d.out", merge, i))
+		if err != nil {
+			log.Fatalf("Could not read result of cube %d: %s", i, err)
+		}
+		results[i], err = logic.ParseSolverOutput(f, cs.NumVars)
+		f.Close()
+		if err != nil {
+			log.Fatalf("Could not read result of cube %d: %s", i, err)
+		}
+		if !results[i].Satisfiable && prooffile != "" {
+			results[i].Proof, err = ioutil.ReadFile(fmt.Sprintf("%s_%d.drat", merge, i))
+			if err != nil {
+				log.Fatalf("Could not read proof of cube %d: %s", i, err)
+			}
+		}
+	}
+
+	var proof *os.File
+	var err error
+	if prooffile != "" {
+		proof, err = os.Create(prooffile)
+		if err != nil {
+			log.Fatalf("Could not create proof file: %s", err)
+		}
+		defer proof.Close()
+	}
+	var satisfiable bool
+	var model []bool
+	if proof != nil {
+		satisfiable, model, err = logic.MergeCubeResults(cs, split, results, proof)
+	} else {
+		satisfiable, model, err = logic.MergeCubeResults(cs, split, results, nil)
+	}
+	if err != nil {
+		log.Fatalf("Could not merge cube results: %s", err)
+	}
+	printModel(cs, satisfiable, model)
+	if satisfiable || proof == nil {
+		return
+	}
+	if _, err := proof.Seek(0, 0); err != nil {
+		log.Fatalf("Could not reread proof file: %s", err)
+	}
+	if err := logic.CheckDRAT(cs, proof); err != nil {
+		log.Fatalf("Proof check failed: %s", err)
+	}
+	fmt.Println("Proof verified")
+}
+
+// The variables of model are those of cs.
+func printModel(cs *logic.ClauseSet, sat bool, model []bool) {
+	if !sat {
+		fmt.Println("UNSATISFIABLE")
+		return
+	}
+	fmt.Println("SATISFIABLE")
+	for _, v := range sortTable(cs.Index) {
+		if model[v.Id] {
+			fmt.Println(v.Name)
+		}
+	}
+}
+
+func checkProof(a logic.Node, prooffile string) {
+	sat, _ := logic.FormatSAT(a)
+	cs, err := logic.ParseDIMACS(strings.NewReader(sat))
+	if err != nil {
+		log.Fatalf("Could not parse generated CNF: %s", err)
+	}
+	f, err := os.Open(prooffile)
+	if err != nil {
+		log.Fatalf("Could not open proof file: %s", err)
+	}
+	defer f.Close()
+	if strings.HasSuffix(prooffile, ".lrat") {
+		err = logic.CheckLRAT(cs, f)
+	} else {
+		err = logic.CheckDRAT(cs, f)
+	}
+	if err != nil {
+		log.Fatalf("Proof check failed: %s", err)
+	}
+	fmt.Println("Proof verified")
+}
+
+func compile(a logic.Node, nnffile string) {
+	sat, _ := logic.FormatSAT(a)
+	cs, err := logic.ParseDIMACS(strings.NewReader(sat))
+	if err != nil {
+		log.Fatalf("Could not parse generated CNF: %s", err)
+	}
+	d := logic.CompileDNNF(cs)
+	f, err := os.Create(nnffile)
+	if err != nil {
+		log.Fatalf("Could not create NNF file: %s", err)
+	}
+	defer f.Close()
+	if err := d.WriteNNF(f); err != nil {
+		log.Fatalf("Could not write NNF file: %s", err)
+	}
+	fmt.Printf("Nodes: %d\n", len(d.Nodes))
+	fmt.Printf("Models: %s\n", d.Count())
+}
+
+// The variables of the NNF file are those of FormatSAT, so a has to
+// be generated from the same input as for --compile.
+func query(a logic.Node, network *stoichio.Network, nnffile, knockout string, t int) {
+	f, err := os.Open(nnffile)
+	if err != nil {
+		log.Fatalf("Could not open NNF file: %s", err)
+	}
+	defer f.Close()
+	d, err := logic.ReadNNF(f)
+	if err != nil {
+		log.Fatalf("Could not read NNF file: %s", err)
+	}
+	cs := logic.Clauses(a)
+	if d.NumVars != cs.NumVars {
+		log.Fatalf("NNF file has %d variables, the formula has %d", d.NumVars, cs.NumVars)
+	}
+	lits := make([]logic.Lit, 0)
+	if len(knockout) > 0 {
+		for _, name := range strings.Split(knockout, ",") {
+			j, e := network.Reaction(strings.TrimSpace(name))
+			if e != nil {
+				log.Fatalf("Invalid knockout list: %s", e)
+			}
+			if l := cs.Lit(fmt.Sprintf(REACTION, network.Reactions[j].ID, t), false); l != 0 {
+				lits = append(lits, l)
+			}
+		}
+	}
+	d = d.Condition(lits...)
+	fmt.Printf("Models: %s\n", d.Count())
+	model, ok := d.MinCard()
+	if !ok {
+		fmt.Println("UNSATISFIABLE")
+		return
+	}
+	for _, l := range lits {
+		model[l.Var()] = l > 0
+	}
+	fmt.Println("Minimal model:")
+	printModel(cs, true, model)
+}
+
+// Every reaction j listed in the file gets a variable a_j which is
+// true with the given probability and required for r_j in the last
+// timestep. Reactions are monotone (A6), so this disables them at
+// all times. The probability is the weighted count projected on the
+// a_j.
+func availability(a logic.Node, network *stoichio.Network, file string, t int) {
+	f, err := os.Open(file)
+	if err != nil {
+		log.Fatalf("Could not open availability file: %s", err)
+	}
+	probs, err := logic.ReadProbabilities(f)
+	f.Close()
+	if err != nil {
+		log.Fatalf("Could not read availability file: %s", err)
+	}
+	b := logic.NewOperation(logic.AND, a)
+	for name := range probs {
+		j, e := network.Reaction(name)
+		if e != nil {
+			log.Fatalf("Invalid availability file: %s", e)
+		}
+		b.PushOperands(logic.NewOperation(logic.IF,
+			logic.NewLeaf(fmt.Sprintf(REACTION, network.Reactions[j].ID, t)),
+			logic.NewLeaf(fmt.Sprintf(AVAILABLE, network.Reactions[j].ID))))
+	}
+	cs := logic.Clauses(b)
+	w := make(logic.Weights)
+	projection := make([]int, 0, len(probs))
+	for name, p := range probs {
+		j, _ := network.Reaction(name)
+		v := cs.Var(fmt.Sprintf(AVAILABLE, network.Reactions[j].ID))
+		w.SetProbability(v, p)
+		projection = append(projection, v)
+	}
+	r := logic.WeightedCount(cs, w, projection)
+	fmt.Printf("Probability: %s (%s)\n", r.RatString(), r.FloatString(10))
+}
+
+// Reactions are monotone over time (A6), so a reaction is used at
+// all iff it is active in the last timestep.
+func printBackbone(a logic.Node, network *stoichio.Network, t int) {
+	cs := logic.Clauses(a)
+	backbone, ok := logic.Backbone(cs)
+	if !ok {
+		fmt.Println("Unsatisfiable")
+		return
+	}
+	fixed := make(map[logic.Lit]bool)
+	for _, l := range backbone {
+		fixed[l] = true
+	}
+	essential, blocked := make([]string, 0), make([]string, 0)
+	for _, r := range network.Reactions {
+		name := fmt.Sprintf(REACTION, r.ID, t)
+		id := r.ID
+		if r.Reversed {
+			id += " (reversed)"
+		}
+		if fixed[cs.Lit(name, true)] {
+			essential = append(essential, id)
+		} else if fixed[cs.Lit(name, false)] {
+			blocked = append(blocked, id)
+		}
+	}
+	fmt.Printf("Essential reactions: %s\n", strings.Join(essential, ", "))
+	fmt.Printf("Blocked reactions: %s\n", strings.Join(blocked, ", "))
+}
+
 func contains(a []int, i int) bool {
 	for _, v := range a {
 		if v == i {
@@ -1,15 +1,18 @@
 package main
 
 import (
+	"./logic"
 	"bufio"
 	"flag"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 )
 
 var (
-	helpFlag = flag.Bool("help", false, "Show this help")
+	helpFlag    = flag.Bool("help", false, "Show this help")
+	formulaFlag = flag.String("formula", "", "Only keep solutions satisfying the formula in this file (as written by logisches_modell -s -save)")
 )
 
 func main() {
@@ -23,6 +26,9 @@ func main() {
 
 	file := flag.Arg(0)
 	solutions := parseSolutions(file)
+	if *formulaFlag != "" {
+		filterFormula(solutions, *formulaFlag)
+	}
 	filter(solutions)
 	for _, s := range solutions {
 		if s == nil {
@@ -53,6 +59,35 @@ func filter(sol []Solution) {
 	}
 }
 
+// Removes the solutions which do not satisfy the formula.
+func filterFormula(sol []Solution, file string) {
+	n, e := logic.LoadFormula(file)
+	if e != nil {
+		panic(e)
+	}
+	p, e := logic.Compile(n)
+	if e != nil {
+		panic(e)
+	}
+	index := make(map[string]int)
+	for _, name := range p.Vars {
+		v, e := strconv.Atoi(name)
+		if e != nil || v <= 0 {
+			panic("Leaf is not a variable number: " + name)
+		}
+		index[name] = v - 1
+	}
+	models := make([][]bool, len(sol))
+	for i := range sol {
+		models[i] = sol[i]
+	}
+	for i, ok := range p.Filter(models, index) {
+		if !ok {
+			sol[i] = nil
+		}
+	}
+}
+
 type Solution []bool
 
 func parseSolutions(file string) []Solution {
@@ -0,0 +1,338 @@
+package stoichio
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+)
+
+// Lumped is an original reaction of a reduced one, whose flux is
+// Factor times the flux of the reduced reaction.
+type Lumped struct {
+	Reaction int
+	Factor   *big.Rat
+}
+
+// Compression is a network reduced by Compress together with the
+// mapping back to the original one. Steady-state flux vectors of the
+// reduced network expand to those of the original network, and its
+// elementary modes to the original elementary modes.
+type Compression struct {
+	// The reduced matrix may have no rows, its number of reactions is
+	// that of Irreversible.
+	Matrix       Matrix
+	Irreversible []bool
+	// Original metabolite of every reduced row
+	Metabolites []int
+	// Original reactions of every reduced reaction
+	Reactions [][]Lumped
+	// Original reactions which can never carry a steady-state flux,
+	// sorted
+	Blocked []int
+
+	reactions int
+}
+
+// Compress repeatedly removes metabolites without reactions, dead-end
+// metabolites (which only one reaction touches, or which can only be
+// produced or only be consumed) together with their reactions, and
+// reactions which are zero in every vector of the kernel. Reactions
+// whose fluxes are proportional in every vector of the kernel (enzyme
+// subsets) are merged into one column, which is irreversible if one
+// of them is; if their directions contradict, they are blocked.
+func Compress(m Matrix, irreversible []bool) (*Compression, error) {
+	for i, row := range m {
+		if len(row) != len(irreversible) {
+			return nil, fmt.Errorf("Row %d has %d reactions, but there are %d reversibilities", i, len(row), len(irreversible))
+		}
+	}
+	c := &Compression{
+		Matrix:       make(Matrix, len(m)),
+		Irreversible: append([]bool(nil), irreversible...),
+		Metabolites:  make([]int, len(m)),
+		Reactions:    make([][]Lumped, len(irreversible)),
+		Blocked:      make([]int, 0),
+		reactions:    len(irreversible),
+	}
+	for i, row := range m {
+		c.Matrix[i] = append([]Cell(nil), row...)
+		c.Metabolites[i] = i
+	}
+	for j := range c.Reactions {
+		c.Reactions[j] = []Lumped{{j, big.NewRat(1, 1)}}
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for c.removeDeadEnds() {
+			changed = true
+		}
+		rref, pivots := c.Matrix.Rat().RREF()
+		merged, e := c.mergeSubsets(nullspace(rref, pivots, len(c.Irreversible)))
+		if e != nil {
+			return nil, e
+		}
+		changed = changed || merged
+	}
+	sort.Ints(c.Blocked)
+	return c, nil
+}
+
+// Removes metabolites without reactions and dead ends with their
+// reactions. Returns whether anything was removed.
+func (c *Compression) removeDeadEnds() bool {
+	blocked := make(map[int]bool)
+	keep := make([]int, 0, len(c.Matrix))
+	for i, row := range c.Matrix {
+		touching, produced, consumed := 0, false, false
+		for j, v := range row {
+			if v == 0 {
+				continue
+			}
+			touching++
+			if v > 0 || !c.Irreversible[j] {
+				produced = true
+			}
+			if v < 0 || !c.Irreversible[j] {
+				consumed = true
+			}
+		}
+		if touching == 0 {
+			continue
+		}
+		if touching == 1 || !produced || !consumed {
+			for j, v := range row {
+				if v != 0 {
+					blocked[j] = true
+				}
+			}
+			continue
+		}
+		keep = append(keep, i)
+	}
+	if len(keep) == len(c.Matrix) {
+		return false
+	}
+	matrix, metabolites := make(Matrix, len(keep)), make([]int, len(keep))
+	for k, i := range keep {
+		matrix[k], metabolites[k] = c.Matrix[i], c.Metabolites[i]
+	}
+	c.Matrix, c.Metabolites = matrix, metabolites
+	columns := make([]int, 0, len(blocked))
+	for j := range blocked {
+		columns = append(columns, j)
+	}
+	c.removeReactions(columns)
+	return true
+}
+
+func (c *Compression) removeReactions(columns []int) {
+	remove := make(map[int]bool)
+	for _, j := range columns {
+		remove[j] = true
+		for _, l := range c.Reactions[j] {
+			c.Blocked = append(c.Blocked, l.Reaction)
+		}
+	}
+	keep := make([]int, 0, len(c.Irreversible))
+	for j := range c.Irreversible {
+		if !remove[j] {
+			keep = append(keep, j)
+		}
+	}
+	for i, row := range c.Matrix {
+		r := make([]Cell, len(keep))
+		for k, j := range keep {
+			r[k] = row[j]
+		}
+		c.Matrix[i] = r
+	}
+	irreversible, reactions := make([]bool, len(keep)), make([][]Lumped, len(keep))
+	for k, j := range keep {
+		irreversible[k], reactions[k] = c.Irreversible[j], c.Reactions[j]
+	}
+	c.Irreversible, c.Reactions = irreversible, reactions
+}
+
+// Merges every enzyme subset into one reaction and removes the
+// blocked ones, those which are zero in the kernel and subsets with
+// contradicting directions. Returns whether anything changed.
+func (c *Compression) mergeSubsets(kernel RatMatrix) (bool, error) {
+	n := len(c.Irreversible)
+	zero := func(j int) bool {
+		for _, x := range kernel {
+			if x[j].Sign() != 0 {
+				return false
+			}
+		}
+		return true
+	}
+	// Returns l with v_j = l v_r for every v of the kernel, or nil.
+	ratio := func(j, r int) *big.Rat {
+		var l *big.Rat
+		for _, x := range kernel {
+			if x[r].Sign() == 0 {
+				if x[j].Sign() != 0 {
+					return nil
+				}
+				continue
+			}
+			q := new(big.Rat).Quo(x[j], x[r])
+			if l == nil {
+				l = q
+			} else if l.Cmp(q) != 0 {
+				return nil
+			}
+		}
+		return l
+	}
+
+	changed := false
+	block := func(columns ...int) {
+		for _, j := range columns {
+			for _, l := range c.Reactions[j] {
+				c.Blocked = append(c.Blocked, l.Reaction)
+			}
+		}
+		changed = true
+	}
+	type subset struct {
+		columns []int
+		factors []*big.Rat
+	}
+	subsets := make([]subset, 0, n)
+	assigned := make([]bool, n)
+	for r := 0; r < n; r++ {
+		if assigned[r] {
+			continue
+		}
+		if zero(r) {
+			block(r)
+			continue
+		}
+		s := subset{[]int{r}, []*big.Rat{big.NewRat(1, 1)}}
+		for j := r + 1; j < n; j++ {
+			if assigned[j] || zero(j) {
+				continue
+			}
+			if l := ratio(j, r); l != nil {
+				s.columns = append(s.columns, j)
+				s.factors = append(s.factors, l)
+				assigned[j] = true
+				changed = true
+			}
+		}
+		forward, backward := false, false
+		for k, j := range s.columns {
+			if c.Irreversible[j] {
+				forward = forward || s.factors[k].Sign() > 0
+				backward = backward || s.factors[k].Sign() < 0
+			}
+		}
+		if forward && backward {
+			block(s.columns...)
+			continue
+		}
+		if backward {
+			for _, f := range s.factors {
+				f.Neg(f)
+			}
+		}
+		subsets = append(subsets, s)
+	}
+	if !changed {
+		return false, nil
+	}
+
+	columns := make(RatMatrix, len(c.Matrix))
+	for i := range columns {
+		columns[i] = make([]*big.Rat, len(subsets))
+	}
+	reactions := make([][]Lumped, len(subsets))
+	irreversible := make([]bool, len(subsets))
+	for k, s := range subsets {
+		for i, row := range c.Matrix {
+			sum := new(big.Rat)
+			for l, j := range s.columns {
+				sum.Add(sum, new(big.Rat).Mul(s.factors[l], big.NewRat(int64(row[j]), 1)))
+			}
+			columns[i][k] = sum
+		}
+		for l, j := range s.columns {
+			irreversible[k] = irreversible[k] || c.Irreversible[j]
+			for _, lumped := range c.Reactions[j] {
+				reactions[k] = append(reactions[k], Lumped{lumped.Reaction, new(big.Rat).Mul(lumped.Factor, s.factors[l])})
+			}
+		}
+	}
+	// Scaling a column to integers scales the flux by the inverse
+	matrix, scale, e := columns.Integral()
+	if e != nil {
+		return false, e
+	}
+	for k, lumped := range reactions {
+		if len(scale) == 0 {
+			break
+		}
+		for _, l := range lumped {
+			l.Factor.Mul(l.Factor, new(big.Rat).SetInt(scale[k]))
+		}
+	}
+	c.Matrix, c.Reactions, c.Irreversible = matrix, reactions, irreversible
+	return true, nil
+}
+
+func (c *Compression) NumReactions() int {
+	return c.reactions
+}
+
+// Expand returns the flux of the original reactions for a flux w of
+// the reduced ones.
+func (c *Compression) Expand(w []*big.Rat) []*big.Rat {
+	v := make([]*big.Rat, c.reactions)
+	for j := range v {
+		v[j] = new(big.Rat)
+	}
+	for k, lumped := range c.Reactions {
+		for _, l := range lumped {
+			v[l.Reaction].Mul(l.Factor, w[k])
+		}
+	}
+	return v
+}
+
+// ExpandMode expands an elementary mode of the reduced network to one
+// of the original network.
+func (c *Compression) ExpandMode(f FluxMode) FluxMode {
+	w := make([]*big.Rat, len(f))
+	for k, x := range f {
+		w[k] = new(big.Rat).SetInt(x)
+	}
+	v := c.Expand(w)
+	lcm := big.NewInt(1)
+	for _, x := range v {
+		gcd := new(big.Int).GCD(nil, nil, lcm, x.Denom())
+		lcm.Mul(lcm, new(big.Int).Quo(x.Denom(), gcd))
+	}
+	r := make([]*big.Int, len(v))
+	for j, x := range v {
+		r[j] = new(big.Int).Mul(x.Num(), lcm)
+		r[j].Quo(r[j], x.Denom())
+	}
+	return FluxMode(newRay(r).v)
+}
+
+// ExpandSupport returns the original reactions active if the given
+// reduced ones are.
+func (c *Compression) ExpandSupport(active []bool) []bool {
+	r := make([]bool, c.reactions)
+	for k, a := range active {
+		if !a {
+			continue
+		}
+		for _, l := range c.Reactions[k] {
+			r[l.Reaction] = true
+		}
+	}
+	return r
+}
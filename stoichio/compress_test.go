@@ -0,0 +1,122 @@
+package stoichio
+
+import (
+	"math/big"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestCompress(t *testing.T) {
+	// -> A, A -> B, B ->, A -> C, C is a dead end, B <-> D, D ->
+	m, _, e := ParseMatrix("[1 -1 0 -1 0 0; 0 1 -1 0 -1 0; 0 0 0 1 0 0; 0 0 0 0 1 -1]")
+	if e != nil {
+		t.Fatal(e)
+	}
+	c, e := Compress(m, []bool{true, true, true, true, false, true})
+	if e != nil {
+		t.Fatal(e)
+	}
+	if !reflect.DeepEqual(c.Blocked, []int{3}) {
+		t.Fatalf("Blocked %v", c.Blocked)
+	}
+	// B <-> D and D -> are one irreversible reaction, uptake and
+	// A -> B another
+	if len(c.Reactions) != 3 || !reflect.DeepEqual(c.Irreversible, []bool{true, true, true}) {
+		t.Fatalf("Reduced to %s, %v, %v", c.Matrix, c.Irreversible, c.Reactions)
+	}
+	if !reflect.DeepEqual(c.Metabolites, []int{1}) || !reflect.DeepEqual(c.Matrix, Matrix{{1, -1, -1}}) {
+		t.Fatalf("Reduced to %s with metabolites %v", c.Matrix, c.Metabolites)
+	}
+	if s := c.ExpandSupport([]bool{true, false, true}); !reflect.DeepEqual(s, []bool{true, true, false, false, true, true}) {
+		t.Fatalf("Expanded support %v", s)
+	}
+	w := []*big.Rat{big.NewRat(2, 1), big.NewRat(1, 1), big.NewRat(1, 1)}
+	v := c.Expand(w)
+	for j, expected := range []int64{2, 2, 1, 0, 1, 1} {
+		if v[j].Cmp(big.NewRat(expected, 1)) != 0 {
+			t.Fatalf("Expanded flux %v", v)
+		}
+	}
+
+	// A <-> B and A <-> 2 B, no dead ends, but zero in the kernel
+	c, e = Compress(Matrix{{-1, -1}, {1, 2}}, []bool{false, false})
+	if e != nil {
+		t.Fatal(e)
+	}
+	if len(c.Reactions) != 0 || !reflect.DeepEqual(c.Blocked, []int{0, 1}) {
+		t.Fatalf("Reduced to %s, %v, blocked %v", c.Matrix, c.Reactions, c.Blocked)
+	}
+}
+
+func TestCompressFractions(t *testing.T) {
+	// -> 3 A, 2 A -> B, B ->
+	c, e := Compress(Matrix{{3, -2, 0}, {0, 1, -1}}, []bool{true, true, true})
+	if e != nil {
+		t.Fatal(e)
+	}
+	if len(c.Reactions) != 1 || len(c.Matrix) != 0 || len(c.Blocked) != 0 {
+		t.Fatalf("Reduced to %s, %v", c.Matrix, c.Reactions)
+	}
+	modes, e := ElementaryModes(c.Matrix, c.Irreversible, EFMOptions{})
+	if e != nil || len(modes) != 1 {
+		t.Fatalf("Modes %v, %v", modes, e)
+	}
+	if f := c.ExpandMode(modes[0]); f.String() != "2 3 3" {
+		t.Fatalf("Expanded mode %s", f)
+	}
+}
+
+func TestCompressModes(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	for n := 0; n < 200; n++ {
+		rows, cols := 1+rng.Intn(5), 2+rng.Intn(7)
+		m := make(Matrix, rows)
+		for i := range m {
+			m[i] = make([]Cell, cols)
+			for j := range m[i] {
+				if rng.Intn(3) == 0 {
+					m[i][j] = Cell(rng.Intn(5) - 2)
+				}
+			}
+		}
+		irreversible := make([]bool, cols)
+		for j := range irreversible {
+			irreversible[j] = rng.Intn(3) != 0
+		}
+		modes, e := ElementaryModes(m, irreversible, EFMOptions{})
+		if e != nil {
+			t.Fatal(e)
+		}
+		c, e := Compress(m, irreversible)
+		if e != nil {
+			t.Fatal(e)
+		}
+		reduced, e := ElementaryModes(c.Matrix, c.Irreversible, EFMOptions{})
+		if e != nil {
+			t.Fatal(e)
+		}
+		expanded := make([]FluxMode, len(reduced))
+		for i, f := range reduced {
+			expanded[i] = c.ExpandMode(f)
+		}
+		s, expected := modeStrings(expanded), modeStrings(modes)
+		sort.Strings(s)
+		sort.Strings(expected)
+		if !reflect.DeepEqual(s, expected) {
+			t.Fatalf("Modes of %s, %v: %q, compressed to %s, %v: %q", m, irreversible, expected, c.Matrix, c.Irreversible, s)
+		}
+		active := make([]bool, cols)
+		for _, f := range modes {
+			for j, a := range f.Support() {
+				active[j] = active[j] || a
+			}
+		}
+		for _, j := range c.Blocked {
+			if active[j] {
+				t.Fatalf("Reaction %d of %s, %v is not blocked", j, m, irreversible)
+			}
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package stoichio
+
+import (
+	"math/big"
+	"sort"
+)
+
+// Coupling of a reaction j to a reaction k as defined by Burgard et
+// al. (2004), Flux coupling analysis of genome-scale metabolic network
+// reconstructions.
+type Coupling int
+
+const (
+	Uncoupled Coupling = iota
+	// v_j / v_k is constant
+	FullyCoupled
+	// v_j is nonzero if and only if v_k is, at varying ratios
+	PartiallyCoupled
+	// A nonzero v_j implies a nonzero v_k, but not vice versa
+	DirectionallyCoupled
+	// A nonzero v_k implies a nonzero v_j, but not vice versa
+	ReverseCoupled
+)
+
+func (c Coupling) String() string {
+	switch c {
+	case FullyCoupled:
+		return "full"
+	case PartiallyCoupled:
+		return "partial"
+	case DirectionallyCoupled:
+		return "->"
+	case ReverseCoupled:
+		return "<-"
+	}
+	return "none"
+}
+
+// CouplingAnalysis holds the coupling of every pair of unblocked
+// reactions. Blocked reactions are uncoupled from every reaction,
+// including themselves.
+type CouplingAnalysis struct {
+	Matrix  [][]Coupling
+	Blocked []bool
+	// Classes of at least two fully or partially coupled reactions,
+	// sorted
+	Groups [][]int
+}
+
+// FluxCoupling computes the coupling of the steady-state fluxes of m
+// from its elementary modes, enumerated on the compressed network.
+// Since every flux is a sign-consistent sum of elementary modes, j is
+// directionally coupled to k if every mode using j uses k, and fully
+// coupled if the ratio of their fluxes is the same in all of them.
+func FluxCoupling(m Matrix, irreversible []bool, opts EFMOptions) (*CouplingAnalysis, error) {
+	c, e := Compress(m, irreversible)
+	if e != nil {
+		return nil, e
+	}
+	modes, e := ElementaryModes(c.Matrix, c.Irreversible, opts)
+	if e != nil {
+		return nil, e
+	}
+	for i, f := range modes {
+		modes[i] = c.ExpandMode(f)
+	}
+	return CouplingFromModes(len(irreversible), modes), nil
+}
+
+// CouplingFromModes classifies the reactions from the complete set of
+// elementary modes of a network with n reactions.
+func CouplingFromModes(n int, modes []FluxMode) *CouplingAnalysis {
+	a := &CouplingAnalysis{
+		Matrix:  make([][]Coupling, n),
+		Blocked: make([]bool, n),
+		Groups:  make([][]int, 0),
+	}
+	// implies[j][k] while every mode using j uses k, ratio[j][k] the
+	// ratio v_j / v_k while it is the same in all those modes
+	implies := make([][]bool, n)
+	ratio := make([][]*big.Rat, n)
+	constant := make([][]bool, n)
+	for j := range implies {
+		a.Matrix[j] = make([]Coupling, n)
+		a.Blocked[j] = true
+		implies[j] = make([]bool, n)
+		ratio[j] = make([]*big.Rat, n)
+		constant[j] = make([]bool, n)
+		for k := range implies[j] {
+			implies[j][k], constant[j][k] = true, true
+		}
+	}
+	for _, f := range modes {
+		for j, x := range f {
+			if x.Sign() == 0 {
+				continue
+			}
+			a.Blocked[j] = false
+			for k, y := range f {
+				if y.Sign() == 0 {
+					implies[j][k] = false
+					continue
+				}
+				q := new(big.Rat).SetFrac(x, y)
+				if ratio[j][k] == nil {
+					ratio[j][k] = q
+				} else if ratio[j][k].Cmp(q) != 0 {
+					constant[j][k] = false
+				}
+			}
+		}
+	}
+
+	group := make([]int, n)
+	for j := range group {
+		group[j] = -1
+	}
+	for j := 0; j < n; j++ {
+		if a.Blocked[j] {
+			continue
+		}
+		for k := 0; k < n; k++ {
+			if a.Blocked[k] {
+				continue
+			}
+			switch {
+			case implies[j][k] && implies[k][j] && constant[j][k]:
+				a.Matrix[j][k] = FullyCoupled
+			case implies[j][k] && implies[k][j]:
+				a.Matrix[j][k] = PartiallyCoupled
+			case implies[j][k]:
+				a.Matrix[j][k] = DirectionallyCoupled
+			case implies[k][j]:
+				a.Matrix[j][k] = ReverseCoupled
+			}
+			if j == k || group[j] >= 0 || !implies[j][k] || !implies[k][j] {
+				continue
+			}
+			if group[k] < 0 {
+				group[k] = len(a.Groups)
+				a.Groups = append(a.Groups, []int{k})
+			}
+			group[j] = group[k]
+			a.Groups[group[k]] = append(a.Groups[group[k]], j)
+		}
+	}
+	for _, g := range a.Groups {
+		sort.Ints(g)
+	}
+	return a
+}
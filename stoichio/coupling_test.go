@@ -0,0 +1,63 @@
+package stoichio
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFluxCoupling(t *testing.T) {
+	// 0: -> A, 1: A -> B, 2: B ->, 3: A -> C, 4: C ->, 5: -> C,
+	// 6: B -> D, D is a dead end
+	m, _, e := ParseMatrix("[1 -1 0 -1 0 0 0; 0 1 -1 0 0 0 -1; 0 0 0 1 -1 1 0; 0 0 0 0 0 0 1]")
+	if e != nil {
+		t.Fatal(e)
+	}
+	for _, rank := range []bool{false, true} {
+		a, e := FluxCoupling(m, []bool{true, true, true, true, true, true, true}, EFMOptions{RankTest: rank})
+		if e != nil {
+			t.Fatal(e)
+		}
+		if !reflect.DeepEqual(a.Blocked, []bool{false, false, false, false, false, false, true}) {
+			t.Fatalf("Blocked %v", a.Blocked)
+		}
+		// 1 and 2 are fully coupled and need 0, 3 needs 0 and 4, 5
+		// needs 4
+		expected := [][]Coupling{
+			{FullyCoupled, ReverseCoupled, ReverseCoupled, ReverseCoupled, Uncoupled, Uncoupled, Uncoupled},
+			{DirectionallyCoupled, FullyCoupled, FullyCoupled, Uncoupled, Uncoupled, Uncoupled, Uncoupled},
+			{DirectionallyCoupled, FullyCoupled, FullyCoupled, Uncoupled, Uncoupled, Uncoupled, Uncoupled},
+			{DirectionallyCoupled, Uncoupled, Uncoupled, FullyCoupled, DirectionallyCoupled, Uncoupled, Uncoupled},
+			{Uncoupled, Uncoupled, Uncoupled, ReverseCoupled, FullyCoupled, ReverseCoupled, Uncoupled},
+			{Uncoupled, Uncoupled, Uncoupled, Uncoupled, DirectionallyCoupled, FullyCoupled, Uncoupled},
+			make([]Coupling, 7),
+		}
+		if !reflect.DeepEqual(a.Matrix, expected) {
+			t.Fatalf("Coupling %v", a.Matrix)
+		}
+		if !reflect.DeepEqual(a.Groups, [][]int{{1, 2}}) {
+			t.Fatalf("Groups %v", a.Groups)
+		}
+	}
+}
+
+func TestPartialCoupling(t *testing.T) {
+	// 0: -> A + B, 1: -> A + 2 B, 2: A ->, 3: B ->, so 2 and 3 are
+	// partially coupled, and both uptakes need both
+	m := Matrix{{1, 1, -1, 0}, {1, 2, 0, -1}}
+	a, e := FluxCoupling(m, []bool{true, true, true, true}, EFMOptions{})
+	if e != nil {
+		t.Fatal(e)
+	}
+	expected := [][]Coupling{
+		{FullyCoupled, Uncoupled, DirectionallyCoupled, DirectionallyCoupled},
+		{Uncoupled, FullyCoupled, DirectionallyCoupled, DirectionallyCoupled},
+		{ReverseCoupled, ReverseCoupled, FullyCoupled, PartiallyCoupled},
+		{ReverseCoupled, ReverseCoupled, PartiallyCoupled, FullyCoupled},
+	}
+	if !reflect.DeepEqual(a.Matrix, expected) || !reflect.DeepEqual(a.Groups, [][]int{{2, 3}}) {
+		t.Fatalf("Coupling %v, groups %v", a.Matrix, a.Groups)
+	}
+	if PartiallyCoupled.String() != "partial" || ReverseCoupled.String() != "<-" {
+		t.Fatal("Wrong names")
+	}
+}
@@ -0,0 +1,299 @@
+package stoichio
+
+import (
+	"fmt"
+	"math/big"
+	"math/bits"
+	"sort"
+	"strings"
+)
+
+// FluxMode is a flux vector scaled to coprime integers.
+type FluxMode []*big.Int
+
+func (f FluxMode) Support() []bool {
+	r := make([]bool, len(f))
+	for j, v := range f {
+		r[j] = v.Sign() != 0
+	}
+	return r
+}
+
+func (f FluxMode) String() string {
+	s := make([]string, len(f))
+	for j, v := range f {
+		s[j] = v.String()
+	}
+	return strings.Join(s, " ")
+}
+
+// Unscale converts a mode of a matrix whose columns were scaled by
+// the given factors, see RatMatrix.Integral, to the fluxes of the
+// unscaled reactions, which are the smallest integral ones.
+func (f FluxMode) Unscale(scale []*big.Int) FluxMode {
+	v := make([]*big.Int, len(f))
+	for j, x := range f {
+		v[j] = new(big.Int).Set(x)
+		if scale != nil {
+			v[j].Mul(v[j], scale[j])
+		}
+	}
+	return FluxMode(newRay(v).v)
+}
+
+// SupportString writes the support as solutionfilter reads it, the
+// 1-based reactions negated if inactive and terminated by 0.
+func (f FluxMode) SupportString() string {
+	s := ""
+	for j, v := range f {
+		if v.Sign() == 0 {
+			s += "-"
+		}
+		s += fmt.Sprintf("%d ", j+1)
+	}
+	return s + "0"
+}
+
+type EFMOptions struct {
+	// Test adjacency by the rank of the kernel restricted to the common
+	// zeros instead of comparing with the supports of all other modes.
+	RankTest bool
+	// Fail if an iteration produces more intermediate modes, 0 for no
+	// limit.
+	MaxModes int
+}
+
+type bitset []uint64
+
+func newBitset(n int) bitset {
+	return make(bitset, (n+63)/64)
+}
+
+func (b bitset) set(i int) {
+	b[i/64] |= 1 << uint(i%64)
+}
+
+func (b bitset) has(i int) bool {
+	return b[i/64]&(1<<uint(i%64)) != 0
+}
+
+type ray struct {
+	v    []*big.Int
+	supp bitset
+}
+
+func newRay(v []*big.Int) *ray {
+	gcd := new(big.Int)
+	for _, x := range v {
+		if x.Sign() != 0 {
+			gcd.GCD(nil, nil, gcd, new(big.Int).Abs(x))
+		}
+	}
+	r := &ray{v, newBitset(len(v))}
+	for j, x := range v {
+		if x.Sign() != 0 {
+			r.supp.set(j)
+			if gcd.Cmp(big.NewInt(1)) > 0 {
+				x.Quo(x, gcd)
+			}
+		}
+	}
+	return r
+}
+
+// A reaction of the network with the reversible reactions split into
+// a forward and a backward one.
+type splitReaction struct {
+	orig int
+	sign Cell
+}
+
+// ElementaryModes enumerates the elementary flux modes of m with the
+// nullspace approach of Wagner, a double description method starting
+// from a kernel basis. Reversible reactions are split into two
+// irreversible ones; modes of reversible reactions only are returned
+// with both signs. The modes are sorted by their flux vectors.
+func ElementaryModes(m Matrix, irreversible []bool, opts EFMOptions) ([]FluxMode, error) {
+	for i, row := range m {
+		if len(row) != len(irreversible) {
+			return nil, fmt.Errorf("Row %d has %d reactions, but there are %d reversibilities", i, len(row), len(irreversible))
+		}
+	}
+	reactions := make([]splitReaction, 0, len(irreversible))
+	for j, irr := range irreversible {
+		reactions = append(reactions, splitReaction{j, 1})
+		if !irr {
+			reactions = append(reactions, splitReaction{j, -1})
+		}
+	}
+	split := make(Matrix, len(m))
+	for i := range m {
+		split[i] = make([]Cell, len(reactions))
+		for k, r := range reactions {
+			split[i][k] = r.sign * m[i][r.orig]
+		}
+	}
+
+	n := len(reactions)
+	rref, pivots := split.Rat().RREF()
+	kernel := nullspace(rref, pivots, n)
+	d := len(kernel)
+	if d == 0 {
+		return []FluxMode{}, nil
+	}
+
+	// The kernel basis has the identity on the free reactions, so
+	// their constraints hold for the cone it generates.
+	processed := newBitset(n)
+	remaining := make(map[int]bool)
+	for _, c := range pivots {
+		remaining[c] = true
+	}
+	for j := 0; j < n; j++ {
+		if !remaining[j] {
+			processed.set(j)
+		}
+	}
+	rays := make([]*ray, d)
+	for k, x := range kernel {
+		lcm := big.NewInt(1)
+		for _, v := range x {
+			gcd := new(big.Int).GCD(nil, nil, lcm, v.Denom())
+			lcm.Mul(lcm, new(big.Int).Quo(v.Denom(), gcd))
+		}
+		v := make([]*big.Int, n)
+		for j, q := range x {
+			v[j] = new(big.Int).Mul(q.Num(), lcm)
+			v[j].Quo(v[j], q.Denom())
+		}
+		rays[k] = newRay(v)
+	}
+
+	for len(remaining) > 0 {
+		// The reaction with the fewest combinations next
+		j, best := -1, 0
+		for c := range remaining {
+			pos, neg := 0, 0
+			for _, r := range rays {
+				switch r.v[c].Sign() {
+				case 1:
+					pos++
+				case -1:
+					neg++
+				}
+			}
+			if j < 0 || pos*neg < best || pos*neg == best && c < j {
+				j, best = c, pos*neg
+			}
+		}
+
+		pos, neg := make([]*ray, 0), make([]*ray, 0)
+		next := make([]*ray, 0, len(rays))
+		for _, r := range rays {
+			switch r.v[j].Sign() {
+			case 1:
+				pos = append(pos, r)
+			case -1:
+				neg = append(neg, r)
+			}
+			if r.v[j].Sign() >= 0 {
+				next = append(next, r)
+			}
+		}
+		for _, p := range pos {
+			for _, q := range neg {
+				if !adjacent(p, q, rays, processed, kernel, d, opts.RankTest) {
+					continue
+				}
+				a, b := new(big.Int).Neg(q.v[j]), p.v[j]
+				v := make([]*big.Int, n)
+				for k := range v {
+					v[k] = new(big.Int).Mul(a, p.v[k])
+					v[k].Add(v[k], new(big.Int).Mul(b, q.v[k]))
+				}
+				next = append(next, newRay(v))
+				if opts.MaxModes > 0 && len(next) > opts.MaxModes {
+					return nil, fmt.Errorf("More than %d intermediate modes", opts.MaxModes)
+				}
+			}
+		}
+		rays = next
+		processed.set(j)
+		delete(remaining, j)
+	}
+
+	modes := make([]FluxMode, 0, len(rays))
+	for _, r := range rays {
+		f := make(FluxMode, len(irreversible))
+		for j := range f {
+			f[j] = new(big.Int)
+		}
+		cycle := false
+		for k, s := range reactions {
+			if r.v[k].Sign() == 0 {
+				continue
+			}
+			if f[s.orig].Sign() != 0 {
+				// Forward and backward direction of one reaction
+				cycle = true
+				break
+			}
+			f[s.orig].Mul(r.v[k], big.NewInt(int64(s.sign)))
+		}
+		if !cycle {
+			modes = append(modes, f)
+		}
+	}
+	sort.Slice(modes, func(a, b int) bool {
+		for j := range modes[a] {
+			if c := modes[a][j].Cmp(modes[b][j]); c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+	return modes, nil
+}
+
+// Returns whether p and q span a face of dimension 2 of the cone of
+// the processed constraints, so that their combination is extreme in
+// the next one.
+func adjacent(p, q *ray, rays []*ray, processed bitset, kernel RatMatrix, d int, rankTest bool) bool {
+	union := make(bitset, len(processed))
+	zeros := 0
+	for w := range union {
+		union[w] = (p.supp[w] | q.supp[w]) & processed[w]
+		zeros += bits.OnesCount64(processed[w] &^ union[w])
+	}
+	if zeros < d-2 {
+		return false
+	}
+	if rankTest {
+		z := make(RatMatrix, d)
+		for k := range z {
+			z[k] = make([]*big.Rat, 0, zeros)
+			for j := range kernel[k] {
+				if processed.has(j) && !union.has(j) {
+					z[k] = append(z[k], kernel[k][j])
+				}
+			}
+		}
+		return z.Rank() == d-2
+	}
+	for _, r := range rays {
+		if r == p || r == q {
+			continue
+		}
+		contained := true
+		for w := range union {
+			if r.supp[w]&processed[w]&^union[w] != 0 {
+				contained = false
+				break
+			}
+		}
+		if contained {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,166 @@
+package stoichio
+
+import (
+	"math/big"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func modeStrings(modes []FluxMode) []string {
+	r := make([]string, len(modes))
+	for i, f := range modes {
+		r[i] = f.String()
+	}
+	return r
+}
+
+func TestElementaryModes(t *testing.T) {
+	for _, c := range []struct {
+		m            string
+		irreversible []bool
+		modes        []string
+	}{
+		// -> A -> B ->
+		{"[1 -1 0; 0 1 -1]", []bool{true, true, true}, []string{"1 1 1"}},
+		// -> A, A <-> B, B <->, A ->
+		{
+			"[1 -1 0 -1; 0 1 -1 0]",
+			[]bool{true, false, false, true},
+			[]string{"0 -1 -1 1", "1 0 0 1", "1 1 1 0"},
+		},
+		// Reversible cycle A <-> B <-> C <-> A
+		{"[-1 0 1; 1 -1 0; 0 1 -1]", []bool{false, false, false}, []string{"-1 -1 -1", "1 1 1"}},
+		// -> 2 A, A -> B, B ->, -> B/2, scaled to -> B
+		{"[2 -1 0 0; 0 1 -1 1/2]", []bool{true, true, true, true}, []string{"0 0 1 1", "1 2 2 0"}},
+		// Nothing can leave
+		{"[1 -1; 0 1]", []bool{true, true}, []string{}},
+	} {
+		m, _, e := ParseMatrix(c.m)
+		if e != nil {
+			t.Fatal(e)
+		}
+		for _, rank := range []bool{false, true} {
+			modes, e := ElementaryModes(m, c.irreversible, EFMOptions{RankTest: rank})
+			if e != nil {
+				t.Fatal(e)
+			}
+			if s := modeStrings(modes); !reflect.DeepEqual(s, c.modes) {
+				t.Fatalf("Modes of %s with rank test %v: %q", c.m, rank, s)
+			}
+		}
+	}
+}
+
+// Returns the modes of m by trying every support: a support is
+// elementary if the kernel of its columns is a line whose vectors
+// have no zeros.
+func bruteForceModes(m Matrix, irreversible []bool) []string {
+	n := m.NumCols()
+	r := make([]string, 0)
+	for s := 1; s < 1<<uint(n); s++ {
+		cols := make([]int, 0)
+		for j := 0; j < n; j++ {
+			if s&(1<<uint(j)) != 0 {
+				cols = append(cols, j)
+			}
+		}
+		sub := make(RatMatrix, len(m))
+		for i := range m {
+			sub[i] = make([]*big.Rat, len(cols))
+			for k, j := range cols {
+				sub[i][k] = big.NewRat(int64(m[i][j]), 1)
+			}
+		}
+		k := sub.Nullspace()
+		if len(k) != 1 {
+			continue
+		}
+		lcm := big.NewInt(1)
+		for _, v := range k[0] {
+			lcm.Mul(lcm, v.Denom())
+		}
+		for _, sign := range []int64{1, -1} {
+			v := make([]*big.Int, n)
+			for j := range v {
+				v[j] = new(big.Int)
+			}
+			ok := true
+			for idx, j := range cols {
+				x := new(big.Rat).Mul(k[0][idx], new(big.Rat).SetInt(lcm))
+				v[j].Mul(x.Num(), big.NewInt(sign))
+				if v[j].Sign() == 0 || v[j].Sign() < 0 && irreversible[j] {
+					ok = false
+				}
+			}
+			if ok {
+				r = append(r, FluxMode(newRay(v).v).String())
+			}
+		}
+	}
+	sort.Strings(r)
+	return r
+}
+
+func TestElementaryModesRandom(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for n := 0; n < 100; n++ {
+		rows, cols := 1+rng.Intn(4), 2+rng.Intn(6)
+		m := make(Matrix, rows)
+		for i := range m {
+			m[i] = make([]Cell, cols)
+			for j := range m[i] {
+				if rng.Intn(2) == 0 {
+					m[i][j] = Cell(rng.Intn(5) - 2)
+				}
+			}
+		}
+		irreversible := make([]bool, cols)
+		for j := range irreversible {
+			irreversible[j] = rng.Intn(3) != 0
+		}
+		expected := bruteForceModes(m, irreversible)
+		for _, rank := range []bool{false, true} {
+			modes, e := ElementaryModes(m, irreversible, EFMOptions{RankTest: rank})
+			if e != nil {
+				t.Fatal(e)
+			}
+			s := modeStrings(modes)
+			sort.Strings(s)
+			if !reflect.DeepEqual(s, expected) {
+				t.Fatalf("Modes of %s, %v with rank test %v: %q, expected %q", m, irreversible, rank, s, expected)
+			}
+		}
+	}
+}
+
+func TestElementaryModesOutput(t *testing.T) {
+	f := FluxMode{big.NewInt(2), big.NewInt(0), big.NewInt(-1)}
+	if f.String() != "2 0 -1" || f.SupportString() != "1 -2 3 0" || !reflect.DeepEqual(f.Support(), []bool{true, false, true}) {
+		t.Fatalf("Wrong output %q, %q", f, f.SupportString())
+	}
+	m := Matrix{{1, -1, 0, 0, 0}, {0, 1, -1, -1, 0}, {0, 0, 1, 1, -1}}
+	if _, e := ElementaryModes(m, []bool{true, true, true, true, true}, EFMOptions{MaxModes: 1}); e == nil {
+		t.Fatal("MaxModes ignored")
+	}
+	if _, e := ElementaryModes(m, []bool{true}, EFMOptions{}); e == nil {
+		t.Fatal("Wrong number of reversibilities accepted")
+	}
+}
+
+func TestUnscale(t *testing.T) {
+	// A -> 0.5 B -> : the scaled second column is [-2 1], so the mode
+	// (2, 1, 1) of the scaled matrix is (2, 2, 1) in real fluxes
+	m, scale, e := ParseMatrix("[1 -1 0; 0 0.5 -1]")
+	if e != nil {
+		t.Fatal(e)
+	}
+	modes, e := ElementaryModes(m, []bool{true, true, true}, EFMOptions{})
+	if e != nil || len(modes) != 1 {
+		t.Fatalf("Got %v, %v", modes, e)
+	}
+	if f := modes[0].Unscale(scale); f.String() != "2 2 1" {
+		t.Fatalf("Unscaled %s to %s", modes[0], f)
+	}
+}
@@ -0,0 +1,83 @@
+package stoichio
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+)
+
+// FBAResult is an optimal steady-state flux distribution.
+type FBAResult struct {
+	Objective float64
+	Flux      []float64
+	// Shadow prices of the metabolites and reduced costs of the
+	// reactions, see LPSolution.
+	Duals        []float64
+	ReducedCosts []float64
+}
+
+// DefaultBounds returns the bounds [0, limit] for irreversible and
+// [-limit, limit] for reversible reactions.
+func DefaultBounds(irreversible []bool, limit float64) ([]float64, []float64) {
+	lower, upper := make([]float64, len(irreversible)), make([]float64, len(irreversible))
+	for j, irr := range irreversible {
+		upper[j] = limit
+		if !irr {
+			lower[j] = -limit
+		}
+	}
+	return lower, upper
+}
+
+// FBA maximizes the flux through the objective reaction subject to
+// m v = 0 and the bounds. Column j of m is scaled by scale[j] as
+// returned by RatMatrix.Integral, or not at all if scale is nil. The
+// bounds and the result refer to the unscaled reactions.
+func FBA(m Matrix, scale []*big.Int, lower, upper []float64, objective int) (*FBAResult, error) {
+	c := make([]float64, len(lower))
+	if objective < 0 || objective >= len(c) {
+		return nil, fmt.Errorf("Objective reaction %d out of range", objective)
+	}
+	c[objective] = 1
+	return optimize(m, scale, lower, upper, c)
+}
+
+// The coefficients of m with the scale of the columns divided out.
+func unscaled(m Matrix, scale []*big.Int) [][]float64 {
+	a := make([][]float64, len(m))
+	for i, row := range m {
+		a[i] = make([]float64, len(row))
+		for j, v := range row {
+			a[i][j] = float64(v)
+			if scale != nil {
+				s, _ := new(big.Float).SetInt(scale[j]).Float64()
+				a[i][j] /= s
+			}
+		}
+	}
+	return a
+}
+
+func optimize(m Matrix, scale []*big.Int, lower, upper, objective []float64) (*FBAResult, error) {
+	lp := &LP{
+		A:         unscaled(m, scale),
+		B:         make([]float64, len(m)),
+		Objective: objective,
+		Lower:     lower,
+		Upper:     upper,
+	}
+	s, e := lp.Solve()
+	if e != nil {
+		return nil, e
+	}
+	if s.Status != Optimal {
+		return nil, fmt.Errorf("Flux balance problem %s", s.Status)
+	}
+	// Round away the noise of the simplex method
+	for j, v := range s.X {
+		if math.Abs(v) < lpTolerance {
+			s.X[j] = 0
+		}
+	}
+	return &FBAResult{s.Value, s.X, s.Duals, s.ReducedCosts}, nil
+}
@@ -0,0 +1,65 @@
+package stoichio
+
+import (
+	"testing"
+)
+
+func TestFBA(t *testing.T) {
+	// 0: -> A, 1: A -> B, 2: A -> 2 B, 3: B ->, 4: A ->
+	m := Matrix{{1, -1, -1, 0, -1}, {0, 1, 2, -1, 0}}
+	lower, upper := DefaultBounds([]bool{true, true, true, true, true}, 1000)
+	upper[0] = 10
+	upper[2] = 4
+	r, e := FBA(m, nil, lower, upper, 3)
+	if e != nil {
+		t.Fatal(e)
+	}
+	// 4 through the better route, the rest of the uptake through the
+	// other one
+	if !near(r.Objective, 14) {
+		t.Fatalf("Objective %g", r.Objective)
+	}
+	for j, v := range []float64{10, 6, 4, 14, 0} {
+		if !near(r.Flux[j], v) {
+			t.Fatalf("Flux %v", r.Flux)
+		}
+	}
+	// Draining a unit of A or of B costs a unit of B export
+	if !near(r.Duals[0], -1) || !near(r.Duals[1], -1) {
+		t.Fatalf("Duals %v", r.Duals)
+	}
+	if !near(r.ReducedCosts[0], 1) || !near(r.ReducedCosts[2], 1) || !near(r.ReducedCosts[4], -1) {
+		t.Fatalf("Reduced costs %v", r.ReducedCosts)
+	}
+
+	if _, e := FBA(m, nil, lower, upper, 5); e == nil {
+		t.Fatal("Objective out of range accepted")
+	}
+	lower[4] = 20
+	if _, e := FBA(m, nil, lower, upper, 3); e == nil {
+		t.Fatal("Infeasible bounds accepted")
+	}
+}
+
+func TestFBAScaled(t *testing.T) {
+	// Parsed as [2 -1] with the second column scaled by 2
+	m, scale, e := ParseMatrix("[1 -0.5]")
+	if e != nil {
+		t.Fatal(e)
+	}
+	lower, upper := DefaultBounds([]bool{true, true}, 10)
+	r, e := FBA(m, scale, lower, upper, 0)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if !near(r.Objective, 5) || !near(r.Flux[0], 5) || !near(r.Flux[1], 10) {
+		t.Fatalf("Objective %g, flux %v", r.Objective, r.Flux)
+	}
+	f, e := FVA(m, scale, lower, upper, FVAOptions{Objective: -1})
+	if e != nil {
+		t.Fatal(e)
+	}
+	if !near(f.Max[0], 5) || !near(f.Max[1], 10) {
+		t.Fatalf("Ranges %v, %v", f.Min, f.Max)
+	}
+}
@@ -0,0 +1,148 @@
+package stoichio
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"runtime"
+	"strconv"
+	"sync"
+)
+
+type FVAOptions struct {
+	// Reaction whose flux has to reach Fraction of its maximum, -1 for
+	// none. A Fraction of 0 also leaves the objective free.
+	Objective int
+	Fraction  float64
+	// Number of LPs solved in parallel, runtime.NumCPU() if 0
+	Workers int
+}
+
+// FVAResult holds the range of the flux of every reaction. Unbounded
+// ranges are infinite.
+type FVAResult struct {
+	// Maximum of the objective, 0 without one
+	Optimum  float64
+	Min, Max []float64
+	// Reactions whose minimum and maximum are both zero
+	Blocked []bool
+}
+
+type fvaJob struct {
+	reaction int
+	sign     float64
+}
+
+// FVA minimizes and maximizes the flux of every reaction subject to
+// m v = 0, the bounds and the objective constraint, solving the LPs
+// with a pool of workers. The scale is that of FBA.
+func FVA(m Matrix, scale []*big.Int, lower, upper []float64, opts FVAOptions) (*FVAResult, error) {
+	n := len(lower)
+	if len(upper) != n {
+		return nil, fmt.Errorf("%d lower, but %d upper bounds", n, len(upper))
+	}
+	r := &FVAResult{
+		Min:     make([]float64, n),
+		Max:     make([]float64, n),
+		Blocked: make([]bool, n),
+	}
+	lower = append([]float64(nil), lower...)
+	if opts.Objective >= 0 && opts.Fraction > 0 {
+		opt, e := FBA(m, scale, lower, upper, opts.Objective)
+		if e != nil {
+			return nil, e
+		}
+		r.Optimum = opt.Objective
+		// Relaxed by the tolerance, so that the optimum itself stays
+		// feasible. The fraction is taken of the distance to zero, so
+		// that it also loosens negative optima.
+		min := opt.Objective - (1-opts.Fraction)*math.Abs(opt.Objective) - lpTolerance*(1+math.Abs(opt.Objective))
+		lower[opts.Objective] = math.Max(lower[opts.Objective], math.Min(min, upper[opts.Objective]))
+	}
+
+	a := unscaled(m, scale)
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	jobs := make(chan fvaJob)
+	errs := make(chan error, workers)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				c := make([]float64, n)
+				c[job.reaction] = job.sign
+				lp := &LP{A: a, B: make([]float64, len(a)), Objective: c, Lower: lower, Upper: upper}
+				s, e := lp.Solve()
+				if e == nil && s.Status == Infeasible {
+					e = fmt.Errorf("Flux variability problem infeasible")
+				}
+				if e != nil {
+					errs <- e
+					// Drain the jobs so that the sender finishes
+					for range jobs {
+					}
+					return
+				}
+				v := math.Inf(int(job.sign))
+				if s.Status == Optimal {
+					v = job.sign * s.Value
+					if math.Abs(v) < lpTolerance {
+						v = 0
+					}
+				}
+				// Every job writes its own cell
+				if job.sign > 0 {
+					r.Max[job.reaction] = v
+				} else {
+					r.Min[job.reaction] = v
+				}
+			}
+		}()
+	}
+	for j := 0; j < n; j++ {
+		jobs <- fvaJob{j, 1}
+		jobs <- fvaJob{j, -1}
+	}
+	close(jobs)
+	wg.Wait()
+	close(errs)
+	if e, ok := <-errs; ok {
+		return nil, e
+	}
+	for j := range r.Blocked {
+		r.Blocked[j] = r.Min[j] == 0 && r.Max[j] == 0
+	}
+	return r, nil
+}
+
+// Write writes one line per reaction with its minimum, maximum and
+// whether it is blocked. Reactions are named by names if given, else
+// by their index like the IDs of NewNetwork. The bounds are rounded
+// to 8 digits, which hides the relaxation of the objective constraint.
+func (r *FVAResult) Write(w io.Writer, names []string) error {
+	if names != nil && len(names) != len(r.Min) {
+		return fmt.Errorf("%d names for %d reactions", len(names), len(r.Min))
+	}
+	if _, e := fmt.Fprintf(w, "%-12s %14s %14s\n", "Reaction", "Min", "Max"); e != nil {
+		return e
+	}
+	for j := range r.Min {
+		name := strconv.Itoa(j)
+		if names != nil {
+			name = names[j]
+		}
+		blocked := ""
+		if r.Blocked[j] {
+			blocked = " blocked"
+		}
+		if _, e := fmt.Fprintf(w, "%-12s %14.8g %14.8g%s\n", name, r.Min[j], r.Max[j], blocked); e != nil {
+			return e
+		}
+	}
+	return nil
+}
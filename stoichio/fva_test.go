@@ -0,0 +1,100 @@
+package stoichio
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFVA(t *testing.T) {
+	// 0: -> A, 1: A -> B, 2: A -> 2 B, 3: B ->, 4: A ->, 5: A -> C
+	m := Matrix{{1, -1, -1, 0, -1, -1}, {0, 1, 2, -1, 0, 0}, {0, 0, 0, 0, 0, 1}}
+	lower, upper := DefaultBounds([]bool{true, true, true, true, true, true}, 1000)
+	upper[0] = 10
+	upper[2] = 4
+	for _, c := range []struct {
+		fraction float64
+		min, max []float64
+	}{
+		{1, []float64{10, 6, 4, 14, 0, 0}, []float64{10, 6, 4, 14, 0, 0}},
+		{0.5, []float64{3.5, 0, 0, 7, 0, 0}, []float64{10, 10, 4, 14, 6.5, 0}},
+		{0, []float64{0, 0, 0, 0, 0, 0}, []float64{10, 10, 4, 14, 10, 0}},
+	} {
+		for _, workers := range []int{1, 4} {
+			r, e := FVA(m, nil, lower, upper, FVAOptions{Objective: 3, Fraction: c.fraction, Workers: workers})
+			if e != nil {
+				t.Fatal(e)
+			}
+			for j := range c.min {
+				if !near(r.Min[j], c.min[j]) || !near(r.Max[j], c.max[j]) {
+					t.Fatalf("Fraction %g: ranges %v, %v", c.fraction, r.Min, r.Max)
+				}
+			}
+			if c.fraction > 0 && !near(r.Optimum, 14) {
+				t.Fatalf("Optimum %g", r.Optimum)
+			}
+			if !r.Blocked[5] || r.Blocked[0] {
+				t.Fatalf("Blocked %v", r.Blocked)
+			}
+		}
+	}
+
+	// Uptake and export of A unbounded
+	upper[0], upper[4] = math.Inf(1), math.Inf(1)
+	r, e := FVA(m, nil, lower, upper, FVAOptions{Objective: -1})
+	if e != nil {
+		t.Fatal(e)
+	}
+	if !math.IsInf(r.Max[0], 1) || r.Min[0] != 0 || r.Max[2] != 4 {
+		t.Fatalf("Ranges %v, %v", r.Min, r.Max)
+	}
+	lower[5] = 1
+	if _, e := FVA(m, nil, lower, upper, FVAOptions{Objective: -1, Workers: 3}); e == nil {
+		t.Fatal("Infeasible bounds accepted")
+	}
+}
+
+func TestFVANegativeOptimum(t *testing.T) {
+	// v_0 = -v_1 with v_1 in [1, 2], so the optimum of v_0 is -1 and
+	// 90% of it allows down to -1.1
+	r, e := FVA(Matrix{{1, 1}}, nil, []float64{-10, 1}, []float64{10, 2}, FVAOptions{Objective: 0, Fraction: 0.9})
+	if e != nil {
+		t.Fatal(e)
+	}
+	if !near(r.Optimum, -1) || !near(r.Min[0], -1.1) || !near(r.Max[0], -1) || !near(r.Min[1], 1) || !near(r.Max[1], 1.1) {
+		t.Fatalf("Optimum %g, ranges %v, %v", r.Optimum, r.Min, r.Max)
+	}
+}
+
+func TestFVAWrite(t *testing.T) {
+	r := &FVAResult{
+		Min:     []float64{0, -1},
+		Max:     []float64{0, math.Inf(1)},
+		Blocked: []bool{true, false},
+	}
+	var b bytes.Buffer
+	if e := r.Write(&b, nil); e != nil {
+		t.Fatal(e)
+	}
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if len(lines) != 3 || !reflect.DeepEqual(strings.Fields(lines[1]), []string{"0", "0", "0", "blocked"}) ||
+		!reflect.DeepEqual(strings.Fields(lines[2]), []string{"1", "-1", "+Inf"}) {
+		t.Fatalf("Wrote %q", b.String())
+	}
+	b.Reset()
+	r.Write(&b, []string{"R_up", "R_down"})
+	if !strings.Contains(b.String(), "R_down") {
+		t.Fatalf("Wrote %q", b.String())
+	}
+	if e := r.Write(&b, []string{"R_up"}); e == nil {
+		t.Fatal("Too few names accepted")
+	}
+	b.Reset()
+	r.Min[1] = 2.4999999945
+	r.Write(&b, nil)
+	if !strings.Contains(b.String(), " 2.5 ") {
+		t.Fatalf("Wrote %q", b.String())
+	}
+}
@@ -0,0 +1,149 @@
+package stoichio
+
+import (
+	"math/big"
+)
+
+func (m RatMatrix) numCols() int {
+	if len(m) == 0 {
+		return 0
+	}
+	return len(m[0])
+}
+
+func (m RatMatrix) Transpose() RatMatrix {
+	r := make(RatMatrix, m.numCols())
+	for j := range r {
+		r[j] = make([]*big.Rat, len(m))
+		for i := range m {
+			r[j][i] = new(big.Rat).Set(m[i][j])
+		}
+	}
+	return r
+}
+
+// Multiplies every row with the least common multiple of its
+// denominators, which keeps the row space.
+func (m RatMatrix) integerRows() [][]*big.Int {
+	r := make([][]*big.Int, len(m))
+	for i, row := range m {
+		lcm := big.NewInt(1)
+		for _, v := range row {
+			gcd := new(big.Int).GCD(nil, nil, lcm, v.Denom())
+			lcm.Mul(lcm, new(big.Int).Quo(v.Denom(), gcd))
+		}
+		r[i] = make([]*big.Int, len(row))
+		for j, v := range row {
+			r[i][j] = new(big.Int).Mul(v.Num(), lcm)
+			r[i][j].Quo(r[i][j], v.Denom())
+		}
+	}
+	return r
+}
+
+// RREF returns the reduced row echelon form of m and the columns of
+// its pivots, one per nonzero row. It runs fraction-free Gauss-Jordan
+// elimination over the integers, dividing every update exactly by the
+// previous pivot (Bareiss), and only divides by the pivots at the end,
+// so that the coefficients do not grow beyond the minors of m.
+func (m RatMatrix) RREF() (RatMatrix, []int) {
+	a := m.integerRows()
+	rows, cols := len(a), m.numCols()
+	prev := big.NewInt(1)
+	pivots := make([]int, 0)
+	f, t := new(big.Int), new(big.Int)
+	for c := 0; c < cols && len(pivots) < rows; c++ {
+		r := len(pivots)
+		p := r
+		for p < rows && a[p][c].Sign() == 0 {
+			p++
+		}
+		if p == rows {
+			continue
+		}
+		a[r], a[p] = a[p], a[r]
+		pivot := a[r][c]
+		for i := range a {
+			if i == r {
+				continue
+			}
+			f.Set(a[i][c])
+			for j, v := range a[i] {
+				v.Mul(v, pivot)
+				v.Sub(v, t.Mul(f, a[r][j]))
+				v.Quo(v, prev)
+			}
+		}
+		// Row r changes in later steps
+		prev = new(big.Int).Set(pivot)
+		pivots = append(pivots, c)
+	}
+
+	r := make(RatMatrix, rows)
+	for i := range r {
+		r[i] = make([]*big.Rat, cols)
+		for j := range r[i] {
+			if i < len(pivots) {
+				r[i][j] = new(big.Rat).SetFrac(a[i][j], a[i][pivots[i]])
+			} else {
+				r[i][j] = new(big.Rat)
+			}
+		}
+	}
+	return r, pivots
+}
+
+func (m RatMatrix) Rank() int {
+	_, pivots := m.RREF()
+	return len(pivots)
+}
+
+// Nullspace returns a basis of the vectors x with m x = 0, one per
+// row. The basis vector of a free column has a one there and zeros in
+// the other free columns.
+func (m RatMatrix) Nullspace() RatMatrix {
+	rref, pivots := m.RREF()
+	return nullspace(rref, pivots, m.numCols())
+}
+
+func nullspace(rref RatMatrix, pivots []int, cols int) RatMatrix {
+	pivot := make([]bool, cols)
+	for _, c := range pivots {
+		pivot[c] = true
+	}
+	r := make(RatMatrix, 0, cols-len(pivots))
+	for f := 0; f < cols; f++ {
+		if pivot[f] {
+			continue
+		}
+		x := make([]*big.Rat, cols)
+		for j := range x {
+			x[j] = new(big.Rat)
+		}
+		x[f].SetInt64(1)
+		for k, c := range pivots {
+			x[c].Neg(rref[k][f])
+		}
+		r = append(r, x)
+	}
+	return r
+}
+
+// LeftNullspace returns a basis of the vectors y with y m = 0, one
+// per row. For a stoichiometric matrix these are the conservation
+// relations.
+func (m RatMatrix) LeftNullspace() RatMatrix {
+	return m.Transpose().Nullspace()
+}
+
+func (m Matrix) Rank() int {
+	return m.Rat().Rank()
+}
+
+func (m Matrix) Nullspace() RatMatrix {
+	return m.Rat().Nullspace()
+}
+
+func (m Matrix) LeftNullspace() RatMatrix {
+	return m.Rat().LeftNullspace()
+}
@@ -0,0 +1,134 @@
+package stoichio
+
+import (
+	"math/big"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func mustParseRat(t *testing.T, s string) RatMatrix {
+	m, e := ParseRatMatrix(s)
+	if e != nil {
+		t.Fatal(e)
+	}
+	return m
+}
+
+func ratEqual(a, b RatMatrix) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if len(a[i]) != len(b[i]) {
+			return false
+		}
+		for j := range a[i] {
+			if a[i][j].Cmp(b[i][j]) != 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Returns whether every row x of basis has m x = 0.
+func annihilates(m, basis RatMatrix) bool {
+	for _, x := range basis {
+		for _, row := range m {
+			sum, t := new(big.Rat), new(big.Rat)
+			for j, v := range row {
+				sum.Add(sum, t.Mul(v, x[j]))
+			}
+			if sum.Sign() != 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func TestLinearAlgebra(t *testing.T) {
+	for _, c := range []struct {
+		m, rref       string
+		pivots        []int
+		nullspace     string
+		leftNullspace string
+	}{
+		// Linear pathway -> A -> B ->
+		{"[1 -1 0; 0 1 -1]", "[1 0 -1; 0 1 -1]", []int{0, 1}, "[1 1 1]", ""},
+		// A <-> B, A + B is conserved
+		{"[-1 1; 1 -1]", "[1 -1; 0 0]", []int{0}, "[1 1]", "[1 1]"},
+		// A + ATP -> B + ADP, B + ADP -> C + ATP, C ->, -> A
+		{
+			"[-1 0 0 1; 1 -1 0 0; 0 1 -1 0; -1 1 0 0; 1 -1 0 0]",
+			"[1 0 0 -1; 0 1 0 -1; 0 0 1 -1; 0 0 0 0; 0 0 0 0]",
+			[]int{0, 1, 2},
+			"[1 1 1 1]",
+			"[0 1 0 1 0; 0 -1 0 0 1]",
+		},
+		// Fractions and a zero column
+		{"[0 1/2 1; 0 1/3 2/3]", "[0 1 2; 0 0 0]", []int{1}, "[1 0 0; 0 -2 1]", "[-2/3 1]"},
+	} {
+		m := mustParseRat(t, c.m)
+		rref, pivots := m.RREF()
+		if !ratEqual(rref, mustParseRat(t, c.rref)) || !reflect.DeepEqual(pivots, c.pivots) {
+			t.Fatalf("RREF of %s: %v, %v", c.m, rref, pivots)
+		}
+		if m.Rank() != len(c.pivots) {
+			t.Fatalf("Rank of %s is %d", c.m, m.Rank())
+		}
+		expected := RatMatrix{}
+		if c.nullspace != "" {
+			expected = mustParseRat(t, c.nullspace)
+		}
+		if k := m.Nullspace(); !ratEqual(k, expected) {
+			t.Fatalf("Nullspace of %s is %v", c.m, k)
+		}
+		expected = RatMatrix{}
+		if c.leftNullspace != "" {
+			expected = mustParseRat(t, c.leftNullspace)
+		}
+		if l := m.LeftNullspace(); !ratEqual(l, expected) {
+			t.Fatalf("Left nullspace of %s is %v", c.m, l)
+		}
+	}
+}
+
+func TestLinearAlgebraRandom(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for n := 0; n < 200; n++ {
+		rows, cols := 1+rng.Intn(7), 1+rng.Intn(7)
+		m := make(Matrix, rows)
+		for i := range m {
+			m[i] = make([]Cell, cols)
+			for j := range m[i] {
+				if rng.Intn(2) == 0 {
+					m[i][j] = Cell(rng.Intn(9) - 4)
+				}
+			}
+		}
+		// Dependent rows and columns
+		if rows > 2 {
+			for j := range m[0] {
+				m[rows-1][j] = 2*m[0][j] - 3*m[1][j]
+			}
+		}
+		r := m.Rat()
+		rank := m.Rank()
+		k, l := m.Nullspace(), m.LeftNullspace()
+		if rank != r.Transpose().Rank() || len(k) != cols-rank || len(l) != rows-rank {
+			t.Fatalf("%s has rank %d, nullity %d, left nullity %d", m, rank, len(k), len(l))
+		}
+		if !annihilates(r, k) || !annihilates(r.Transpose(), l) {
+			t.Fatalf("Wrong nullspaces %v, %v of %s", k, l, m)
+		}
+		if len(k) > 0 && k.Rank() != len(k) || len(l) > 0 && l.Rank() != len(l) {
+			t.Fatalf("Dependent nullspace basis of %s", m)
+		}
+		rref, pivots := r.RREF()
+		if again, p := rref.RREF(); !ratEqual(again, rref) || !reflect.DeepEqual(p, pivots) {
+			t.Fatalf("RREF of %s is not reduced: %v", m, rref)
+		}
+	}
+}
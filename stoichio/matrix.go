@@ -3,13 +3,13 @@ package stoichio
 import (
 	"bufio"
 	"fmt"
+	"math/big"
 	"os"
 	"regexp"
-	"strconv"
 	"strings"
 )
 
-type Cell int8
+type Cell int64
 type Matrix [][]Cell
 
 func cleanString(ms string) string {
@@ -18,22 +18,15 @@ func cleanString(ms string) string {
 	return ms
 }
 
-func ParseMatrix(ms string) (Matrix, error) {
-	ms = cleanString(ms)
-	rowstrings := strings.Split(ms, ";")
-	r := make([][]Cell, len(rowstrings))
-	for i, rowstring := range rowstrings {
-		cellstrings := strings.Fields(rowstring)
-		r[i] = make([]Cell, len(cellstrings))
-		for j, cellstring := range cellstrings {
-			v, e := strconv.ParseInt(cellstring, 0, 64)
-			if e != nil {
-				return nil, e
-			}
-			r[i][j] = Cell(v)
-		}
+// ParseMatrix reads rows separated by semicolons. Coefficients may
+// be fractional, see ParseCoefficient; columns with fractions are
+// scaled to integers by the returned factors as by RatMatrix.Integral.
+func ParseMatrix(ms string) (Matrix, []*big.Int, error) {
+	r, e := ParseRatMatrix(ms)
+	if e != nil {
+		return nil, nil, e
 	}
-	return Matrix(r), nil
+	return r.Integral()
 }
 
 func (m Matrix) NumCols() int {
@@ -80,24 +73,26 @@ func ParseIrreversible(is string) []bool {
 	return r
 }
 
-func ReadFile(file string) (Matrix, []bool, error) {
+// ReadFile reads the matrix line and the reversibility line. The
+// factors are those of ParseMatrix.
+func ReadFile(file string) (Matrix, []*big.Int, []bool, error) {
 	f, e := os.Open(file)
 	if e != nil {
-		return nil, nil, e
+		return nil, nil, nil, e
 	}
 	defer f.Close()
 	r := bufio.NewReader(f)
 	matrixstring, prefix, e := r.ReadLine()
 	if e != nil || prefix {
-		return nil, nil, fmt.Errorf("Could not read matrix: %s", e)
+		return nil, nil, nil, fmt.Errorf("Could not read matrix: %s", e)
 	}
 	irreversiblestring, prefix, e := r.ReadLine()
 	if e != nil || prefix {
-		return nil, nil, fmt.Errorf("Could not read reactions: %s", e)
+		return nil, nil, nil, fmt.Errorf("Could not read reactions: %s", e)
 	}
-	matrix, err := ParseMatrix(string(matrixstring))
+	matrix, scale, err := ParseMatrix(string(matrixstring))
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
-	return matrix, ParseIrreversible(string(irreversiblestring)), nil
+	return matrix, scale, ParseIrreversible(string(irreversiblestring)), nil
 }
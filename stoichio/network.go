@@ -0,0 +1,150 @@
+package stoichio
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+type Metabolite struct {
+	ID, Name, Compartment string
+}
+
+type Reaction struct {
+	ID, Name   string
+	Reversible bool
+	// Reversed reactions run backwards in the source, their column
+	// and bounds refer to the forward direction, see SBMLModel.
+	Reversed               bool
+	LowerBound, UpperBound float64
+}
+
+// Network is a stoichiometric matrix together with its metabolites
+// (rows) and reactions (columns).
+type Network struct {
+	Matrix      Matrix
+	Metabolites []Metabolite
+	Reactions   []Reaction
+	// Factors by which the columns of Matrix were scaled to integers,
+	// see RatMatrix.Integral, nil if none were. The bounds of the
+	// reactions refer to the scaled columns.
+	Scale []*big.Int
+}
+
+// NewNetwork uses the 0-based indices as IDs of the metabolites and
+// reactions, so that they are named as before. The bounds are only
+// limited by the irreversibility.
+func NewNetwork(m Matrix, irreversible []bool) *Network {
+	n := &Network{
+		Matrix:      m,
+		Metabolites: make([]Metabolite, m.NumRows()),
+		Reactions:   make([]Reaction, m.NumCols()),
+	}
+	for i := range n.Metabolites {
+		n.Metabolites[i].ID = strconv.Itoa(i)
+	}
+	for j := range n.Reactions {
+		n.Reactions[j].ID = strconv.Itoa(j)
+		n.Reactions[j].Reversible = !irreversible[j]
+		n.Reactions[j].LowerBound, n.Reactions[j].UpperBound = math.Inf(-1), math.Inf(1)
+		if irreversible[j] {
+			n.Reactions[j].LowerBound = 0
+		}
+	}
+	return n
+}
+
+// ReadNetwork reads SBML if the name of the file ends in .xml or
+// .sbml, else the matrix format of ReadFile.
+func ReadNetwork(file string) (*Network, error) {
+	if strings.HasSuffix(file, ".xml") || strings.HasSuffix(file, ".sbml") {
+		m, e := ReadSBMLFile(file)
+		if e != nil {
+			return nil, e
+		}
+		return m.Network(), nil
+	}
+	m, scale, irreversible, e := ReadFile(file)
+	if e != nil {
+		return nil, e
+	}
+	if len(irreversible) != m.NumCols() {
+		return nil, fmt.Errorf("%d reactions, but %d reversibilities", m.NumCols(), len(irreversible))
+	}
+	n := NewNetwork(m, irreversible)
+	n.Scale = scale
+	return n, nil
+}
+
+func (n *Network) Irreversible() []bool {
+	r := make([]bool, len(n.Reactions))
+	for j, reaction := range n.Reactions {
+		r[j] = !reaction.Reversible
+	}
+	return r
+}
+
+// Metabolite returns the index of the metabolite with the given ID,
+// or else with the given display name if only one has it, or else
+// with the given index.
+func (n *Network) Metabolite(name string) (int, error) {
+	ids := make([]string, len(n.Metabolites))
+	names := make([]string, len(n.Metabolites))
+	for i, m := range n.Metabolites {
+		ids[i], names[i] = m.ID, m.Name
+	}
+	return lookup("metabolite", name, ids, names)
+}
+
+// Reaction looks up reactions like Metabolite does metabolites.
+func (n *Network) Reaction(name string) (int, error) {
+	ids := make([]string, len(n.Reactions))
+	names := make([]string, len(n.Reactions))
+	for j, r := range n.Reactions {
+		ids[j], names[j] = r.ID, r.Name
+	}
+	return lookup("reaction", name, ids, names)
+}
+
+func lookup(kind, name string, ids, names []string) (int, error) {
+	for i, id := range ids {
+		if id == name {
+			return i, nil
+		}
+	}
+	found := -1
+	for i, n := range names {
+		if n != name || n == "" {
+			continue
+		}
+		if found >= 0 {
+			return 0, fmt.Errorf("Name %q of more than one %s", name, kind)
+		}
+		found = i
+	}
+	if found >= 0 {
+		return found, nil
+	}
+	if i, e := strconv.Atoi(name); e == nil && i >= 0 && i < len(ids) {
+		return i, nil
+	}
+	return 0, fmt.Errorf("Unknown %s %q", kind, name)
+}
+
+// MetaboliteLabel returns the display name of the i-th metabolite,
+// or its ID if it has none.
+func (n *Network) MetaboliteLabel(i int) string {
+	if n.Metabolites[i].Name != "" {
+		return n.Metabolites[i].Name
+	}
+	return n.Metabolites[i].ID
+}
+
+func (n *Network) ReactionLabel(j int) string {
+	if n.Reactions[j].Name != "" {
+		return n.Reactions[j].Name
+	}
+	return n.Reactions[j].ID
+}
@@ -0,0 +1,58 @@
+package stoichio
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestNetworkLookup(t *testing.T) {
+	n, e := ReadNetwork("testdata/toy.xml")
+	if e != nil {
+		t.Fatal(e)
+	}
+	r := n.Reactions[1]
+	if n.Metabolites[2] != (Metabolite{"O2", "Oxygen", "c"}) || r.ID != "R_conv" || r.Name != "A to B" || !r.Reversible {
+		t.Fatalf("Wrong names %v, %v", n.Metabolites, n.Reactions)
+	}
+	if r = n.Reactions[2]; !r.Reversed || r.Reversible || r.LowerBound != 0 || r.UpperBound != 10 || n.Reactions[0].Reversed {
+		t.Fatalf("Wrong direction or bounds %v", n.Reactions)
+	}
+	for name, expected := range map[string]int{"O2": 2, "Oxygen": 2, "B": 1, "1": 1} {
+		if i, e := n.Metabolite(name); e != nil || i != expected {
+			t.Fatalf("Metabolite(%q) = %d, %v", name, i, e)
+		}
+	}
+	if j, e := n.Reaction("A uptake"); e != nil || j != 0 {
+		t.Fatalf("Reaction returned %d, %v", j, e)
+	}
+	for _, name := range []string{"C", "3", "-1"} {
+		if _, e := n.Metabolite(name); e == nil {
+			t.Fatalf("Metabolite(%q) found", name)
+		}
+	}
+	n.Metabolites[1].Name = "A"
+	if _, e := n.Metabolite("A"); e != nil {
+		t.Fatal("IDs have to take precedence over names")
+	}
+	n.Metabolites[0].ID = "X"
+	if _, e := n.Metabolite("A"); e == nil {
+		t.Fatal("Ambiguous name found")
+	}
+	if len(n.Scale) != len(n.Reactions) {
+		t.Fatalf("Scale %v not carried over", n.Scale)
+	}
+	if !reflect.DeepEqual(n.Irreversible(), []bool{true, false, true, true}) {
+		t.Fatalf("Wrong irreversibility %v", n.Irreversible())
+	}
+}
+
+func TestNewNetwork(t *testing.T) {
+	n := NewNetwork(Matrix{{1, -1}}, []bool{false, true})
+	if n.Metabolites[0].ID != "0" || n.Reactions[1].ID != "1" || !n.Reactions[0].Reversible || n.Reactions[1].Reversible {
+		t.Fatalf("Wrong defaults %v, %v", n.Metabolites, n.Reactions)
+	}
+	if !math.IsInf(n.Reactions[0].LowerBound, -1) || n.Reactions[1].LowerBound != 0 || !math.IsInf(n.Reactions[1].UpperBound, 1) {
+		t.Fatalf("Wrong bounds %v", n.Reactions)
+	}
+}
@@ -0,0 +1,96 @@
+package stoichio
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// RatMatrix holds exact rational coefficients, as parsed from the
+// input before they are made integral.
+type RatMatrix [][]*big.Rat
+
+// ParseCoefficient parses an integer (with an optional base prefix
+// as accepted by strconv.ParseInt, so 010 is 8), a decimal such as
+// 0.5 or 1e-3, or a fraction p/q.
+func ParseCoefficient(s string) (*big.Rat, error) {
+	if v, e := strconv.ParseInt(s, 0, 64); e == nil {
+		return big.NewRat(v, 1), nil
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return nil, fmt.Errorf("Invalid coefficient %q", s)
+	}
+	return r, nil
+}
+
+func ParseRatMatrix(ms string) (RatMatrix, error) {
+	ms = cleanString(ms)
+	rowstrings := strings.Split(ms, ";")
+	r := make(RatMatrix, len(rowstrings))
+	for i, rowstring := range rowstrings {
+		cellstrings := strings.Fields(rowstring)
+		r[i] = make([]*big.Rat, len(cellstrings))
+		for j, cellstring := range cellstrings {
+			v, e := ParseCoefficient(cellstring)
+			if e != nil {
+				return nil, e
+			}
+			r[i][j] = v
+		}
+	}
+	return r, nil
+}
+
+// Integral scales every column with the least common multiple of the
+// denominators of its coefficients. Scaling a reaction by a positive
+// factor keeps the signs and the supports of all flux modes. It
+// returns the factors and fails if a scaled coefficient does not fit
+// into a Cell.
+func (m RatMatrix) Integral() (Matrix, []*big.Int, error) {
+	r := make(Matrix, len(m))
+	ncols := 0
+	for i, row := range m {
+		r[i] = make([]Cell, len(row))
+		if len(row) > ncols {
+			ncols = len(row)
+		}
+	}
+	scale := make([]*big.Int, ncols)
+	for j := range scale {
+		scale[j] = big.NewInt(1)
+		for _, row := range m {
+			if j >= len(row) {
+				continue
+			}
+			d := row[j].Denom()
+			gcd := new(big.Int).GCD(nil, nil, scale[j], d)
+			scale[j].Mul(scale[j], new(big.Int).Quo(d, gcd))
+		}
+		for i, row := range m {
+			if j >= len(row) {
+				continue
+			}
+			v := new(big.Int).Mul(row[j].Num(), scale[j])
+			v.Quo(v, row[j].Denom())
+			if !v.IsInt64() {
+				return nil, nil, fmt.Errorf("Coefficient %s of metabolite %d in reaction %d out of range", v, i, j)
+			}
+			r[i][j] = Cell(v.Int64())
+		}
+	}
+	return r, scale, nil
+}
+
+// Rat returns the coefficients of m as rationals.
+func (m Matrix) Rat() RatMatrix {
+	r := make(RatMatrix, len(m))
+	for i, row := range m {
+		r[i] = make([]*big.Rat, len(row))
+		for j, cell := range row {
+			r[i][j] = big.NewRat(int64(cell), 1)
+		}
+	}
+	return r
+}
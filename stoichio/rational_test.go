@@ -0,0 +1,61 @@
+package stoichio
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+func TestParseMatrix(t *testing.T) {
+	m, scale, e := ParseMatrix("[-1 0.5 128; 1 -1/3 010]")
+	if e != nil {
+		t.Fatal(e)
+	}
+	expected := Matrix{{-1, 3, 128}, {1, -2, 8}}
+	if !reflect.DeepEqual(m, expected) {
+		t.Fatalf("Got %s, expected %s", m, expected)
+	}
+	if scale[0].Int64() != 1 || scale[1].Int64() != 6 || scale[2].Int64() != 1 {
+		t.Fatalf("Wrong scale %v", scale)
+	}
+	if v, e := ParseCoefficient("0x10"); e != nil || v.Cmp(big.NewRat(16, 1)) != 0 {
+		t.Fatalf("ParseCoefficient(0x10) = %v, %v", v, e)
+	}
+	if supp := m.Col(1).Supp(); !reflect.DeepEqual(supp, []int{0, 1}) {
+		t.Fatalf("Wrong support %v", supp)
+	}
+}
+
+func TestIntegral(t *testing.T) {
+	r, e := ParseRatMatrix("[1/4 2; 1/6 1.5]")
+	if e != nil {
+		t.Fatal(e)
+	}
+	m, scale, e := r.Integral()
+	if e != nil {
+		t.Fatal(e)
+	}
+	if scale[0].Int64() != 12 || scale[1].Int64() != 2 || !reflect.DeepEqual(m, Matrix{{3, 4}, {2, 3}}) {
+		t.Fatalf("Got %s scaled by %v", m, scale)
+	}
+	for i, row := range r {
+		for j, v := range row {
+			if new(big.Rat).Mul(v, new(big.Rat).SetInt(scale[j])).Cmp(big.NewRat(int64(m[i][j]), 1)) != 0 {
+				t.Fatalf("Cell %d, %d scaled wrong", i, j)
+			}
+		}
+	}
+}
+
+func TestParseMatrixErrors(t *testing.T) {
+	for _, s := range []string{
+		"[1 a; 1 1]",
+		"[1 1/0]",
+		"[9223372036854775808]",
+		"[1/2; 9223372036854775807]",
+	} {
+		if m, _, e := ParseMatrix(s); e == nil {
+			t.Fatalf("ParseMatrix(%q) returned %s", s, m)
+		}
+	}
+}
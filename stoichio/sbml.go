@@ -0,0 +1,314 @@
+package stoichio
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"os"
+	"strconv"
+)
+
+// SBMLModel is the stoichiometry of an SBML model. Rows are the
+// species without boundary condition, columns the reactions, both in
+// document order.
+type SBMLModel struct {
+	ID           string
+	Matrix       Matrix
+	Irreversible []bool
+	Species      []string
+	Reactions    []string
+	// Flux bounds from the FBC package, -Inf and +Inf if not given.
+	// Reactions which can only run backwards are turned around, so
+	// that their column, bounds and irreversibility refer to the
+	// forward direction; Reversed marks them.
+	LowerBounds, UpperBounds []float64
+	Reversed                 []bool
+	// Columns with fractional stoichiometries are scaled to integers
+	// by these factors, see RatMatrix.Integral. The bounds refer to
+	// the scaled columns.
+	Scale []*big.Int
+
+	// Names and compartments for Network
+	species   []sbmlSpecies
+	reactions []sbmlReaction
+}
+
+type sbmlDocument struct {
+	XMLName xml.Name   `xml:"sbml"`
+	Level   int        `xml:"level,attr"`
+	Version int        `xml:"version,attr"`
+	Model   *sbmlModel `xml:"model"`
+}
+
+type sbmlModel struct {
+	ID         string          `xml:"id,attr"`
+	Species    []sbmlSpecies   `xml:"listOfSpecies>species"`
+	Reactions  []sbmlReaction  `xml:"listOfReactions>reaction"`
+	Parameters []sbmlParameter `xml:"listOfParameters>parameter"`
+	// FBC version 1
+	FluxBounds []sbmlFluxBound `xml:"listOfFluxBounds>fluxBound"`
+}
+
+type sbmlSpecies struct {
+	ID                string `xml:"id,attr"`
+	Name              string `xml:"name,attr"`
+	Compartment       string `xml:"compartment,attr"`
+	BoundaryCondition bool   `xml:"boundaryCondition,attr"`
+}
+
+type sbmlReaction struct {
+	ID         string                 `xml:"id,attr"`
+	Name       string                 `xml:"name,attr"`
+	Reversible *bool                  `xml:"reversible,attr"`
+	Reactants  []sbmlSpeciesReference `xml:"listOfReactants>speciesReference"`
+	Products   []sbmlSpeciesReference `xml:"listOfProducts>speciesReference"`
+	// FBC version 2, ids of parameters
+	LowerFluxBound string `xml:"lowerFluxBound,attr"`
+	UpperFluxBound string `xml:"upperFluxBound,attr"`
+}
+
+type sbmlSpeciesReference struct {
+	Species           string    `xml:"species,attr"`
+	Stoichiometry     *string   `xml:"stoichiometry,attr"`
+	Constant          *bool     `xml:"constant,attr"`
+	StoichiometryMath *struct{} `xml:"stoichiometryMath"`
+}
+
+type sbmlParameter struct {
+	ID    string  `xml:"id,attr"`
+	Value *string `xml:"value,attr"`
+}
+
+type sbmlFluxBound struct {
+	Reaction  string `xml:"reaction,attr"`
+	Operation string `xml:"operation,attr"`
+	Value     string `xml:"value,attr"`
+}
+
+func ReadSBMLFile(file string) (*SBMLModel, error) {
+	f, e := os.Open(file)
+	if e != nil {
+		return nil, e
+	}
+	defer f.Close()
+	return ReadSBML(f)
+}
+
+// ReadSBML reads an SBML Level 2 or 3 document. Kinetic laws, rules,
+// events and modifiers are ignored. Stoichiometries have to be
+// constant numbers.
+func ReadSBML(r io.Reader) (*SBMLModel, error) {
+	var doc sbmlDocument
+	if e := xml.NewDecoder(r).Decode(&doc); e != nil {
+		return nil, fmt.Errorf("Could not parse SBML: %s", e)
+	}
+	if doc.Level != 2 && doc.Level != 3 {
+		return nil, fmt.Errorf("Unsupported SBML level %d", doc.Level)
+	}
+	if doc.Model == nil {
+		return nil, fmt.Errorf("SBML document has no model")
+	}
+	model := doc.Model
+	m := &SBMLModel{
+		ID:        model.ID,
+		reactions: model.Reactions,
+	}
+
+	rows := make(map[string]int)
+	boundary := make(map[string]bool)
+	for _, s := range model.Species {
+		if _, ok := rows[s.ID]; ok || boundary[s.ID] {
+			return nil, fmt.Errorf("Species %s defined twice", s.ID)
+		}
+		if s.BoundaryCondition {
+			boundary[s.ID] = true
+			continue
+		}
+		rows[s.ID] = len(m.Species)
+		m.Species = append(m.Species, s.ID)
+		m.species = append(m.species, s)
+	}
+	if len(m.Species) == 0 || len(model.Reactions) == 0 {
+		return nil, fmt.Errorf("Model has no balanced species or no reactions")
+	}
+
+	params := make(map[string]float64)
+	for _, p := range model.Parameters {
+		if p.Value == nil {
+			continue
+		}
+		v, e := strconv.ParseFloat(*p.Value, 64)
+		if e != nil {
+			return nil, fmt.Errorf("Parameter %s: invalid value %q", p.ID, *p.Value)
+		}
+		params[p.ID] = v
+	}
+
+	n := len(model.Reactions)
+	rat := make(RatMatrix, len(m.Species))
+	for i := range rat {
+		rat[i] = make([]*big.Rat, n)
+		for j := range rat[i] {
+			rat[i][j] = new(big.Rat)
+		}
+	}
+	m.Irreversible = make([]bool, n)
+	m.LowerBounds = make([]float64, n)
+	m.UpperBounds = make([]float64, n)
+	m.Reversed = make([]bool, n)
+	columns := make(map[string]int)
+	for j, reaction := range model.Reactions {
+		if _, ok := columns[reaction.ID]; ok {
+			return nil, fmt.Errorf("Reaction %s defined twice", reaction.ID)
+		}
+		columns[reaction.ID] = j
+		m.Reactions = append(m.Reactions, reaction.ID)
+		if reaction.Reversible == nil && doc.Level == 3 {
+			return nil, fmt.Errorf("Reaction %s: reversible not given", reaction.ID)
+		}
+		// Level 2 reactions are reversible by default
+		m.Irreversible[j] = reaction.Reversible != nil && !*reaction.Reversible
+
+		for _, refs := range []struct {
+			list []sbmlSpeciesReference
+			sign int64
+		}{{reaction.Reactants, -1}, {reaction.Products, 1}} {
+			for _, ref := range refs.list {
+				v, e := stoichiometry(doc.Level, ref)
+				if e != nil {
+					return nil, fmt.Errorf("Reaction %s: %s", reaction.ID, e)
+				}
+				if boundary[ref.Species] {
+					continue
+				}
+				i, ok := rows[ref.Species]
+				if !ok {
+					return nil, fmt.Errorf("Reaction %s: unknown species %s", reaction.ID, ref.Species)
+				}
+				v.Mul(v, big.NewRat(refs.sign, 1))
+				rat[i][j].Add(rat[i][j], v)
+			}
+		}
+
+		m.LowerBounds[j], m.UpperBounds[j] = math.Inf(-1), math.Inf(1)
+		for _, b := range []struct {
+			id    string
+			bound *float64
+		}{{reaction.LowerFluxBound, &m.LowerBounds[j]}, {reaction.UpperFluxBound, &m.UpperBounds[j]}} {
+			if b.id == "" {
+				continue
+			}
+			v, ok := params[b.id]
+			if !ok {
+				return nil, fmt.Errorf("Reaction %s: unknown flux bound parameter %s", reaction.ID, b.id)
+			}
+			*b.bound = v
+		}
+	}
+
+	for _, b := range model.FluxBounds {
+		j, ok := columns[b.Reaction]
+		if !ok {
+			return nil, fmt.Errorf("Flux bound for unknown reaction %s", b.Reaction)
+		}
+		v, e := strconv.ParseFloat(b.Value, 64)
+		if e != nil {
+			return nil, fmt.Errorf("Flux bound of %s: invalid value %q", b.Reaction, b.Value)
+		}
+		switch b.Operation {
+		case "greaterEqual", "greater":
+			m.LowerBounds[j] = v
+		case "lessEqual", "less":
+			m.UpperBounds[j] = v
+		case "equal":
+			m.LowerBounds[j], m.UpperBounds[j] = v, v
+		default:
+			return nil, fmt.Errorf("Flux bound of %s: unsupported operation %q", b.Reaction, b.Operation)
+		}
+	}
+
+	for j := range model.Reactions {
+		if m.Irreversible[j] && m.LowerBounds[j] < 0 {
+			m.LowerBounds[j] = 0
+		}
+		if m.LowerBounds[j] > m.UpperBounds[j] {
+			return nil, fmt.Errorf("Reaction %s: lower flux bound above upper bound", m.Reactions[j])
+		}
+		switch {
+		case m.LowerBounds[j] >= 0:
+			m.Irreversible[j] = true
+		case m.UpperBounds[j] <= 0:
+			m.Irreversible[j] = true
+			m.Reversed[j] = true
+			m.LowerBounds[j], m.UpperBounds[j] = -m.UpperBounds[j], -m.LowerBounds[j]
+			for i := range rat {
+				rat[i][j].Neg(rat[i][j])
+			}
+		}
+	}
+
+	var e error
+	m.Matrix, m.Scale, e = rat.Integral()
+	if e != nil {
+		return nil, e
+	}
+	for j, scale := range m.Scale {
+		f, _ := new(big.Float).SetInt(scale).Float64()
+		m.LowerBounds[j] /= f
+		m.UpperBounds[j] /= f
+	}
+	return m, nil
+}
+
+func stoichiometry(level int, ref sbmlSpeciesReference) (*big.Rat, error) {
+	if ref.StoichiometryMath != nil {
+		return nil, fmt.Errorf("stoichiometryMath of %s is not supported", ref.Species)
+	}
+	if level == 3 && ref.Constant != nil && !*ref.Constant {
+		return nil, fmt.Errorf("non-constant stoichiometry of %s is not supported", ref.Species)
+	}
+	if ref.Stoichiometry == nil {
+		if level == 3 {
+			return nil, fmt.Errorf("stoichiometry of %s not given", ref.Species)
+		}
+		return big.NewRat(1, 1), nil
+	}
+	f, e := strconv.ParseFloat(*ref.Stoichiometry, 64)
+	if e != nil || math.IsInf(f, 0) || math.IsNaN(f) || f < 0 {
+		return nil, fmt.Errorf("invalid stoichiometry %q of %s", *ref.Stoichiometry, ref.Species)
+	}
+	// Doubles like 0.1 are meant as the decimal they are written as
+	v, ok := new(big.Rat).SetString(*ref.Stoichiometry)
+	if !ok {
+		v = new(big.Rat).SetFloat64(f)
+	}
+	return v, nil
+}
+
+// Network returns the matrix with the IDs, names and compartments of
+// the species and the IDs, names, bounds and directions of the
+// reactions.
+func (m *SBMLModel) Network() *Network {
+	n := &Network{
+		Matrix:      m.Matrix,
+		Metabolites: make([]Metabolite, len(m.species)),
+		Reactions:   make([]Reaction, len(m.reactions)),
+		Scale:       m.Scale,
+	}
+	for i, s := range m.species {
+		n.Metabolites[i] = Metabolite{ID: s.ID, Name: s.Name, Compartment: s.Compartment}
+	}
+	for j, r := range m.reactions {
+		n.Reactions[j] = Reaction{
+			ID:         r.ID,
+			Name:       r.Name,
+			Reversible: !m.Irreversible[j],
+			Reversed:   m.Reversed[j],
+			LowerBound: m.LowerBounds[j],
+			UpperBound: m.UpperBounds[j],
+		}
+	}
+	return n
+}
@@ -0,0 +1,75 @@
+package stoichio
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadSBML(t *testing.T) {
+	m, e := ReadSBMLFile("testdata/toy.xml")
+	if e != nil {
+		t.Fatal(e)
+	}
+	if !reflect.DeepEqual(m.Species, []string{"A", "B", "O2"}) ||
+		!reflect.DeepEqual(m.Reactions, []string{"R_up", "R_conv", "R_back", "R_o2"}) {
+		t.Fatalf("Wrong ids %v, %v", m.Species, m.Reactions)
+	}
+	// R_back only runs backwards, so it is turned into an uptake of B
+	expected := Matrix{
+		{1, -2, 0, 0},
+		{0, 2, 1, 0},
+		{0, -1, 0, 1},
+	}
+	if !reflect.DeepEqual(m.Matrix, expected) {
+		t.Fatalf("Got %s, expected %s", m.Matrix, expected)
+	}
+	if !reflect.DeepEqual(m.Irreversible, []bool{true, false, true, true}) ||
+		!reflect.DeepEqual(m.Reversed, []bool{false, false, true, false}) {
+		t.Fatalf("Wrong directions %v, %v", m.Irreversible, m.Reversed)
+	}
+	if m.LowerBounds[2] != 0 || m.UpperBounds[2] != 10 || m.UpperBounds[0] != 10 ||
+		!math.IsInf(m.LowerBounds[1], -1) || !math.IsInf(m.UpperBounds[3], 1) {
+		t.Fatalf("Wrong bounds %v, %v", m.LowerBounds, m.UpperBounds)
+	}
+}
+
+func TestReadSBMLLevel2(t *testing.T) {
+	doc := `<sbml level="2" version="4"><model id="m">
+<listOfSpecies><species id="A"/><species id="B"/></listOfSpecies>
+<listOfReactions>
+<reaction id="R1"><listOfReactants><speciesReference species="A" stoichiometry="2"/></listOfReactants>
+<listOfProducts><speciesReference species="B"/></listOfProducts></reaction>
+<reaction id="R2" reversible="false"><listOfReactants><speciesReference species="B"/></listOfReactants></reaction>
+</listOfReactions></model></sbml>`
+	m, e := ReadSBML(strings.NewReader(doc))
+	if e != nil {
+		t.Fatal(e)
+	}
+	if !reflect.DeepEqual(m.Matrix, Matrix{{-2, 0}, {1, -1}}) || !reflect.DeepEqual(m.Irreversible, []bool{false, true}) {
+		t.Fatalf("Got %s, %v", m.Matrix, m.Irreversible)
+	}
+}
+
+func TestReadSBMLErrors(t *testing.T) {
+	reaction := func(body string) string {
+		return `<sbml level="2" version="4"><model><listOfSpecies><species id="A"/></listOfSpecies>
+<listOfReactions><reaction id="R">` + body + `</reaction></listOfReactions></model></sbml>`
+	}
+	for _, doc := range []string{
+		`<sbml level="1" version="2"><model/></sbml>`,
+		`<sbml level="2" version="4"></sbml>`,
+		reaction(`<listOfReactants><speciesReference species="X"/></listOfReactants>`),
+		reaction(`<listOfReactants><speciesReference species="A"><stoichiometryMath/></speciesReference></listOfReactants>`),
+		reaction(`<listOfReactants><speciesReference species="A" stoichiometry="-1"/></listOfReactants>`),
+		`<sbml level="3" version="1"><model><listOfSpecies><species id="A"/></listOfSpecies>
+<listOfReactions><reaction id="R" reversible="true"><listOfReactants><speciesReference species="A"/></listOfReactants></reaction></listOfReactions></model></sbml>`,
+		`<sbml level="3" version="1"><model><listOfSpecies><species id="A"/></listOfSpecies>
+<listOfReactions><reaction id="R" reversible="true" lowerFluxBound="lb"/></listOfReactions></model></sbml>`,
+	} {
+		if _, e := ReadSBML(strings.NewReader(doc)); e == nil {
+			t.Fatalf("No error for %s", doc)
+		}
+	}
+}
@@ -0,0 +1,321 @@
+package stoichio
+
+import (
+	"fmt"
+	"math"
+)
+
+// LP is the linear program maximize Objective x subject to A x = B and
+// Lower <= x <= Upper. Bounds may be infinite.
+type LP struct {
+	A            [][]float64
+	B            []float64
+	Objective    []float64
+	Lower, Upper []float64
+}
+
+type LPStatus int
+
+const (
+	Optimal LPStatus = iota
+	Infeasible
+	Unbounded
+)
+
+func (s LPStatus) String() string {
+	switch s {
+	case Optimal:
+		return "optimal"
+	case Infeasible:
+		return "infeasible"
+	}
+	return "unbounded"
+}
+
+type LPSolution struct {
+	Status LPStatus
+	Value  float64
+	X      []float64
+	// Duals[i] is the change of the optimum per unit of B[i],
+	// ReducedCosts[j] = Objective[j] - Duals A_j.
+	Duals        []float64
+	ReducedCosts []float64
+}
+
+// Feasibility and optimality tolerance of the simplex method
+const lpTolerance = 1e-9
+
+// Variables x_j of the LP are expressed by the nonnegative columns of
+// the tableau as Lower + y, Upper - y or y - y'.
+type lpColumn struct {
+	variable int
+	sign     float64
+}
+
+type tableau struct {
+	m, n int
+	// m rows of B^-1 [A' I] for the columns and the artificials
+	t     [][]float64
+	basis []int
+	// Values of the basic columns
+	x       []float64
+	upper   []float64
+	atUpper []bool
+	cost    []float64
+}
+
+// Solve runs the two-phase primal simplex method for bounded
+// variables on a dense tableau. It uses Dantzig's rule and switches to
+// Bland's rule after a run of degenerate pivots, so that it does not
+// cycle.
+func (lp *LP) Solve() (*LPSolution, error) {
+	m, n := len(lp.A), len(lp.Objective)
+	if len(lp.B) != m || len(lp.Lower) != n || len(lp.Upper) != n {
+		return nil, fmt.Errorf("LP with %d rows and %d variables, but %d right-hand sides and %d, %d bounds", m, n, len(lp.B), len(lp.Lower), len(lp.Upper))
+	}
+	for i, row := range lp.A {
+		if len(row) != n {
+			return nil, fmt.Errorf("Row %d of the LP has %d variables instead of %d", i, len(row), n)
+		}
+	}
+
+	columns := make([]lpColumn, 0, n)
+	upper := make([]float64, 0, n)
+	offset := make([]float64, n)
+	for j := 0; j < n; j++ {
+		l, u := lp.Lower[j], lp.Upper[j]
+		if l > u || math.IsNaN(l) || math.IsNaN(u) || math.IsInf(l, 1) || math.IsInf(u, -1) {
+			return &LPSolution{Status: Infeasible}, nil
+		}
+		switch {
+		case !math.IsInf(l, -1):
+			columns = append(columns, lpColumn{j, 1})
+			upper = append(upper, u-l)
+			offset[j] = l
+		case !math.IsInf(u, 1):
+			columns = append(columns, lpColumn{j, -1})
+			upper = append(upper, math.Inf(1))
+			offset[j] = u
+		default:
+			columns = append(columns, lpColumn{j, 1}, lpColumn{j, -1})
+			upper = append(upper, math.Inf(1), math.Inf(1))
+		}
+	}
+
+	nc := len(columns)
+	tab := &tableau{
+		m:       m,
+		n:       nc + m,
+		t:       make([][]float64, m),
+		basis:   make([]int, m),
+		x:       make([]float64, m),
+		upper:   append(upper, make([]float64, m)...),
+		atUpper: make([]bool, nc+m),
+		cost:    make([]float64, nc+m),
+	}
+	// Rows are negated where needed to start from nonnegative
+	// artificials.
+	rowSign := make([]float64, m)
+	for i, row := range lp.A {
+		b := lp.B[i]
+		for j, a := range row {
+			b -= a * offset[j]
+		}
+		rowSign[i] = 1
+		if b < 0 {
+			rowSign[i] = -1
+		}
+		tab.t[i] = make([]float64, nc+m)
+		for k, c := range columns {
+			tab.t[i][k] = rowSign[i] * c.sign * row[c.variable]
+		}
+		tab.t[i][nc+i] = 1
+		tab.basis[i] = nc + i
+		tab.x[i] = rowSign[i] * b
+		tab.upper[nc+i] = math.Inf(1)
+	}
+
+	// Phase 1 maximizes the negated sum of the artificials
+	for i := 0; i < m; i++ {
+		tab.cost[nc+i] = -1
+	}
+	if !tab.run(nc + m) {
+		return nil, fmt.Errorf("Phase 1 of the simplex method unbounded")
+	}
+	infeasibility := 0.0
+	for i, b := range tab.basis {
+		if b >= nc {
+			infeasibility += tab.x[i]
+		}
+	}
+	if infeasibility > lpTolerance*float64(1+m) {
+		return &LPSolution{Status: Infeasible}, nil
+	}
+	for i := 0; i < m; i++ {
+		tab.cost[nc+i] = 0
+		tab.upper[nc+i] = 0
+	}
+	for k, c := range columns {
+		tab.cost[k] = c.sign * lp.Objective[c.variable]
+	}
+	if !tab.run(nc) {
+		return &LPSolution{Status: Unbounded}, nil
+	}
+
+	s := &LPSolution{
+		Status:       Optimal,
+		X:            append([]float64(nil), offset...),
+		Duals:        make([]float64, m),
+		ReducedCosts: make([]float64, n),
+	}
+	values := make([]float64, nc+m)
+	for k := range values {
+		if tab.atUpper[k] {
+			values[k] = tab.upper[k]
+		}
+	}
+	for i, b := range tab.basis {
+		values[b] = tab.x[i]
+	}
+	for k, c := range columns {
+		s.X[c.variable] += c.sign * values[k]
+	}
+	for j, x := range s.X {
+		s.Value += lp.Objective[j] * x
+	}
+	// y = c_B B^-1, whose columns are those of the artificials
+	for i := 0; i < m; i++ {
+		y := 0.0
+		for r, b := range tab.basis {
+			y += tab.cost[b] * tab.t[r][nc+i]
+		}
+		s.Duals[i] = rowSign[i] * y
+	}
+	for j := range s.ReducedCosts {
+		d := lp.Objective[j]
+		for i, row := range lp.A {
+			d -= s.Duals[i] * row[j]
+		}
+		s.ReducedCosts[j] = d
+	}
+	return s, nil
+}
+
+// Runs simplex iterations on the first n columns until no reduced
+// cost improves the objective. Returns false if it is unbounded.
+func (tab *tableau) run(n int) bool {
+	degenerate := 0
+	for {
+		bland := degenerate > 50
+		enter, dir, best := -1, 0.0, 0.0
+		for j := 0; j < n; j++ {
+			if tab.isBasic(j) || tab.upper[j] == 0 {
+				continue
+			}
+			d := tab.reducedCost(j)
+			if tab.atUpper[j] {
+				d = -d
+			}
+			if d > lpTolerance && (enter < 0 || !bland && d > best) {
+				enter, best = j, d
+				dir = 1
+				if tab.atUpper[j] {
+					dir = -1
+				}
+				if bland {
+					break
+				}
+			}
+		}
+		if enter < 0 {
+			return true
+		}
+
+		// Largest step keeping the basic columns within their bounds
+		step, leave, leaveUpper := tab.upper[enter], -1, false
+		for i := 0; i < tab.m; i++ {
+			a := dir * tab.t[i][enter]
+			b := tab.basis[i]
+			var limit float64
+			var upper bool
+			switch {
+			case a > lpTolerance:
+				limit = tab.x[i] / a
+			case a < -lpTolerance && !math.IsInf(tab.upper[b], 1):
+				limit, upper = (tab.upper[b]-tab.x[i])/-a, true
+			default:
+				continue
+			}
+			if limit < 0 {
+				limit = 0
+			}
+			if limit < step || limit == step && leave >= 0 && b < tab.basis[leave] {
+				step, leave, leaveUpper = limit, i, upper
+			}
+		}
+		if math.IsInf(step, 1) {
+			return false
+		}
+		if step < lpTolerance {
+			degenerate++
+		} else {
+			degenerate = 0
+		}
+
+		for i := 0; i < tab.m; i++ {
+			tab.x[i] -= dir * step * tab.t[i][enter]
+		}
+		if leave < 0 {
+			tab.atUpper[enter] = !tab.atUpper[enter]
+			continue
+		}
+		value := dir * step
+		if tab.atUpper[enter] {
+			value += tab.upper[enter]
+		}
+		old := tab.basis[leave]
+		tab.pivot(leave, enter)
+		tab.x[leave] = value
+		tab.atUpper[enter] = false
+		tab.atUpper[old] = leaveUpper
+	}
+}
+
+func (tab *tableau) isBasic(j int) bool {
+	for _, b := range tab.basis {
+		if b == j {
+			return true
+		}
+	}
+	return false
+}
+
+func (tab *tableau) reducedCost(j int) float64 {
+	d := tab.cost[j]
+	for i, b := range tab.basis {
+		d -= tab.cost[b] * tab.t[i][j]
+	}
+	return d
+}
+
+func (tab *tableau) pivot(r, j int) {
+	p := tab.t[r][j]
+	row := tab.t[r]
+	for k := range row {
+		row[k] /= p
+	}
+	for i := 0; i < tab.m; i++ {
+		if i == r {
+			continue
+		}
+		f := tab.t[i][j]
+		if f == 0 {
+			continue
+		}
+		for k, v := range row {
+			tab.t[i][k] -= f * v
+		}
+		tab.t[i][j] = 0
+	}
+	tab.basis[r] = j
+}
@@ -0,0 +1,130 @@
+package stoichio
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
+
+func TestSimplex(t *testing.T) {
+	inf := math.Inf(1)
+	for _, c := range []struct {
+		name   string
+		lp     LP
+		status LPStatus
+		value  float64
+		x      []float64
+		duals  []float64
+	}{
+		{"slack", LP{
+			A: [][]float64{{1, 1, 1}}, B: []float64{4}, Objective: []float64{1, 1, 0},
+			Lower: []float64{0, 0, 0}, Upper: []float64{3, 3, inf},
+		}, Optimal, 4, nil, []float64{1}},
+		{"duals", LP{
+			A: [][]float64{{1, 1, 1, 0}, {1, 3, 0, 1}}, B: []float64{4, 6}, Objective: []float64{3, 2, 0, 0},
+			Lower: []float64{0, 0, 0, 0}, Upper: []float64{inf, inf, inf, inf},
+		}, Optimal, 12, []float64{4, 0, 0, 2}, []float64{3, 0}},
+		{"free", LP{
+			A: [][]float64{{1, 1}}, B: []float64{1}, Objective: []float64{1, 0},
+			Lower: []float64{-inf, -2}, Upper: []float64{inf, inf},
+		}, Optimal, 3, []float64{3, -2}, []float64{1}},
+		{"upper only", LP{
+			A: [][]float64{{1, -1}}, B: []float64{-3}, Objective: []float64{1, 1},
+			Lower: []float64{-inf, -inf}, Upper: []float64{-1, 5},
+		}, Optimal, 1, []float64{-1, 2}, []float64{-1}},
+		{"infeasible", LP{
+			A: [][]float64{{1, 1}}, B: []float64{5}, Objective: []float64{1, 0},
+			Lower: []float64{0, 0}, Upper: []float64{2, 2},
+		}, Infeasible, 0, nil, nil},
+		{"unbounded", LP{
+			A: [][]float64{{1, -1}}, B: []float64{0}, Objective: []float64{1, 0},
+			Lower: []float64{0, 0}, Upper: []float64{inf, inf},
+		}, Unbounded, 0, nil, nil},
+	} {
+		s, e := c.lp.Solve()
+		if e != nil {
+			t.Fatal(e)
+		}
+		if s.Status != c.status || s.Status == Optimal && !near(s.Value, c.value) {
+			t.Fatalf("%s: %s with value %g", c.name, s.Status, s.Value)
+		}
+		for j, x := range c.x {
+			if !near(s.X[j], x) {
+				t.Fatalf("%s: solution %v", c.name, s.X)
+			}
+		}
+		for i, y := range c.duals {
+			if !near(s.Duals[i], y) {
+				t.Fatalf("%s: duals %v", c.name, s.Duals)
+			}
+		}
+	}
+}
+
+// Checks optimality by the KKT conditions: x is feasible and the
+// reduced costs do not allow to improve it within the bounds.
+func TestSimplexRandom(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for n := 0; n < 300; n++ {
+		rows, cols := 1+rng.Intn(5), 1+rng.Intn(8)
+		lp := LP{
+			A:         make([][]float64, rows),
+			B:         make([]float64, rows),
+			Objective: make([]float64, cols),
+			Lower:     make([]float64, cols),
+			Upper:     make([]float64, cols),
+		}
+		// Feasible by construction around x0
+		x0 := make([]float64, cols)
+		for j := range x0 {
+			x0[j] = float64(rng.Intn(7) - 3)
+			lp.Objective[j] = float64(rng.Intn(5) - 2)
+			lp.Lower[j], lp.Upper[j] = x0[j]-float64(rng.Intn(3)), x0[j]+float64(rng.Intn(3))
+			switch rng.Intn(4) {
+			case 0:
+				lp.Lower[j] = math.Inf(-1)
+			case 1:
+				lp.Upper[j] = math.Inf(1)
+			}
+		}
+		for i := range lp.A {
+			lp.A[i] = make([]float64, cols)
+			for j := range lp.A[i] {
+				if rng.Intn(2) == 0 {
+					lp.A[i][j] = float64(rng.Intn(5) - 2)
+				}
+				lp.B[i] += lp.A[i][j] * x0[j]
+			}
+		}
+		s, e := lp.Solve()
+		if e != nil {
+			t.Fatal(e)
+		}
+		if s.Status == Infeasible {
+			t.Fatalf("Feasible LP %+v infeasible", lp)
+		}
+		if s.Status == Unbounded {
+			continue
+		}
+		for i, row := range lp.A {
+			sum := 0.0
+			for j, a := range row {
+				sum += a * s.X[j]
+			}
+			if !near(sum, lp.B[i]) {
+				t.Fatalf("Row %d of %+v violated by %v", i, lp, s.X)
+			}
+		}
+		for j, x := range s.X {
+			d := s.ReducedCosts[j]
+			if x < lp.Lower[j]-1e-6 || x > lp.Upper[j]+1e-6 ||
+				d > 1e-6 && !near(x, lp.Upper[j]) || d < -1e-6 && !near(x, lp.Lower[j]) {
+				t.Fatalf("%+v: %v with reduced costs %v not optimal", lp, s.X, s.ReducedCosts)
+			}
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package stoichio
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+)
+
+type Entry struct {
+	Row, Col int
+	Value    Cell
+}
+
+// Sparse stores the nonzeros of a matrix twice, by row (CSR) and by
+// column (CSC), so that both can be iterated without scanning zeros
+// or allocating. It is not meant to be changed after construction.
+type Sparse struct {
+	rows, cols int
+	// The nonzeros of row i are rowIdx[rowStart[i]:rowStart[i+1]]
+	// (their columns) and rowVal, likewise for columns.
+	rowStart, rowIdx []int
+	rowVal           []Cell
+	colStart, colIdx []int
+	colVal           []Cell
+}
+
+// NewSparse returns an error if the rows of m differ in length.
+func NewSparse(m Matrix) (*Sparse, error) {
+	cols := 0
+	if len(m) > 0 {
+		cols = m.NumCols()
+	}
+	entries := make([]Entry, 0)
+	for i, row := range m {
+		if len(row) != cols {
+			return nil, fmt.Errorf("Row %d has %d columns instead of %d", i, len(row), cols)
+		}
+		for j, v := range row {
+			if v != 0 {
+				entries = append(entries, Entry{i, j, v})
+			}
+		}
+	}
+	return NewSparseFromEntries(m.NumRows(), cols, entries)
+}
+
+// NewSparseFromEntries builds a matrix of the given size without
+// going through a dense one. Entries with the same position are
+// added up, which fails if the sum overflows, zeros are dropped.
+func NewSparseFromEntries(rows, cols int, entries []Entry) (*Sparse, error) {
+	if rows < 0 || cols < 0 {
+		return nil, fmt.Errorf("Invalid size %dx%d", rows, cols)
+	}
+	sorted := make([]Entry, 0, len(entries))
+	for _, e := range entries {
+		if e.Row < 0 || e.Row >= rows || e.Col < 0 || e.Col >= cols {
+			return nil, fmt.Errorf("Entry (%d, %d) outside of %dx%d matrix", e.Row, e.Col, rows, cols)
+		}
+		sorted = append(sorted, e)
+	}
+	sort.Slice(sorted, func(a, b int) bool {
+		if sorted[a].Row != sorted[b].Row {
+			return sorted[a].Row < sorted[b].Row
+		}
+		return sorted[a].Col < sorted[b].Col
+	})
+	// Only the total has to fit, so that the order of the entries
+	// does not matter.
+	nonzeros := sorted[:0]
+	sum := new(big.Int)
+	for k := 0; k < len(sorted); {
+		e := sorted[k]
+		sum.SetInt64(0)
+		for ; k < len(sorted) && sorted[k].Row == e.Row && sorted[k].Col == e.Col; k++ {
+			sum.Add(sum, big.NewInt(int64(sorted[k].Value)))
+		}
+		if !sum.IsInt64() {
+			return nil, fmt.Errorf("Entries at (%d, %d) overflow", e.Row, e.Col)
+		}
+		if e.Value = Cell(sum.Int64()); e.Value != 0 {
+			nonzeros = append(nonzeros, e)
+		}
+	}
+
+	s := &Sparse{
+		rows:     rows,
+		cols:     cols,
+		rowStart: make([]int, rows+1),
+		rowIdx:   make([]int, len(nonzeros)),
+		rowVal:   make([]Cell, len(nonzeros)),
+		colStart: make([]int, cols+1),
+		colIdx:   make([]int, len(nonzeros)),
+		colVal:   make([]Cell, len(nonzeros)),
+	}
+	for k, e := range nonzeros {
+		s.rowStart[e.Row+1]++
+		s.colStart[e.Col+1]++
+		s.rowIdx[k], s.rowVal[k] = e.Col, e.Value
+	}
+	for i := 0; i < rows; i++ {
+		s.rowStart[i+1] += s.rowStart[i]
+	}
+	for j := 0; j < cols; j++ {
+		s.colStart[j+1] += s.colStart[j]
+	}
+	// Entries are sorted by row, so every column gets its rows in
+	// ascending order.
+	next := append([]int(nil), s.colStart[:cols]...)
+	for _, e := range nonzeros {
+		k := next[e.Col]
+		s.colIdx[k], s.colVal[k] = e.Row, e.Value
+		next[e.Col]++
+	}
+	return s, nil
+}
+
+func (s *Sparse) NumRows() int {
+	return s.rows
+}
+
+func (s *Sparse) NumCols() int {
+	return s.cols
+}
+
+func (s *Sparse) NumNonzeros() int {
+	return len(s.rowIdx)
+}
+
+// Row returns the columns and values of the nonzeros of row i in
+// ascending order. The slices must not be modified.
+func (s *Sparse) Row(i int) ([]int, []Cell) {
+	return s.rowIdx[s.rowStart[i]:s.rowStart[i+1]], s.rowVal[s.rowStart[i]:s.rowStart[i+1]]
+}
+
+// Col returns the rows and values of the nonzeros of column j in
+// ascending order. The slices must not be modified.
+func (s *Sparse) Col(j int) ([]int, []Cell) {
+	return s.colIdx[s.colStart[j]:s.colStart[j+1]], s.colVal[s.colStart[j]:s.colStart[j+1]]
+}
+
+func (s *Sparse) Get(i, j int) Cell {
+	cols, vals := s.Row(i)
+	k := sort.SearchInts(cols, j)
+	if k < len(cols) && cols[k] == j {
+		return vals[k]
+	}
+	return 0
+}
+
+func (s *Sparse) Dense() Matrix {
+	m := make(Matrix, s.rows)
+	for i := range m {
+		m[i] = make([]Cell, s.cols)
+		cols, vals := s.Row(i)
+		for k, j := range cols {
+			m[i][j] = vals[k]
+		}
+	}
+	return m
+}
@@ -0,0 +1,97 @@
+package stoichio
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestSparse(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for n := 0; n < 50; n++ {
+		m := make(Matrix, 1+rng.Intn(8))
+		cols := 1 + rng.Intn(8)
+		for i := range m {
+			m[i] = make([]Cell, cols)
+			for j := range m[i] {
+				if rng.Intn(3) == 0 {
+					m[i][j] = Cell(rng.Intn(7) - 3)
+				}
+			}
+		}
+		s, e := NewSparse(m)
+		if e != nil {
+			t.Fatal(e)
+		}
+		if !reflect.DeepEqual(s.Dense(), m) {
+			t.Fatalf("Dense() = %s, expected %s", s.Dense(), m)
+		}
+		nonzeros := 0
+		for i := range m {
+			idx, vals := s.Row(i)
+			for k, j := range idx {
+				if vals[k] == 0 || m[i][j] != vals[k] || k > 0 && idx[k-1] >= j {
+					t.Fatalf("Row %d of %s is %v, %v", i, m, idx, vals)
+				}
+			}
+			nonzeros += len(idx)
+			for j := range m[i] {
+				if s.Get(i, j) != m[i][j] {
+					t.Fatalf("Get(%d, %d) of %s is %d", i, j, m, s.Get(i, j))
+				}
+			}
+		}
+		for j := 0; j < cols; j++ {
+			idx, vals := s.Col(j)
+			if !reflect.DeepEqual(idx, m.Col(j).Supp()) {
+				t.Fatalf("Column %d of %s has rows %v", j, m, idx)
+			}
+			for k, i := range idx {
+				if m[i][j] != vals[k] {
+					t.Fatalf("Column %d of %s has values %v", j, m, vals)
+				}
+			}
+		}
+		if nonzeros != s.NumNonzeros() {
+			t.Fatalf("%d nonzeros, expected %d", s.NumNonzeros(), nonzeros)
+		}
+	}
+}
+
+func TestSparseFromEntries(t *testing.T) {
+	s, e := NewSparseFromEntries(2, 3, []Entry{{1, 2, 4}, {0, 1, -1}, {1, 2, -1}, {0, 0, 2}, {0, 0, -2}})
+	if e != nil {
+		t.Fatal(e)
+	}
+	if !reflect.DeepEqual(s.Dense(), Matrix{{0, -1, 0}, {0, 0, 3}}) || s.NumNonzeros() != 2 {
+		t.Fatalf("Got %s", s.Dense())
+	}
+	if _, e := NewSparseFromEntries(2, 3, []Entry{{2, 0, 1}}); e == nil {
+		t.Fatal("Entry outside of the matrix accepted")
+	}
+	if _, e := NewSparseFromEntries(1, 1, []Entry{{0, 0, math.MaxInt64}, {0, 0, 1}}); e == nil {
+		t.Fatal("Overflowing sum accepted")
+	}
+	if _, e := NewSparseFromEntries(1, 1, []Entry{{0, 0, math.MinInt64}, {0, 0, -1}}); e == nil {
+		t.Fatal("Underflowing sum accepted")
+	}
+	for _, entries := range [][]Entry{
+		{{0, 0, math.MaxInt64}, {0, 0, 1}, {0, 0, -1}},
+		{{0, 0, 1}, {0, 0, math.MaxInt64}, {0, 0, -1}},
+		{{0, 0, -1}, {0, 0, 1}, {0, 0, math.MaxInt64}},
+	} {
+		if s, e := NewSparseFromEntries(1, 1, entries); e != nil || s.Dense()[0][0] != math.MaxInt64 {
+			t.Fatalf("Entries %v gave %v, %v", entries, s, e)
+		}
+	}
+	if _, e := NewSparseFromEntries(-1, 2, nil); e == nil {
+		t.Fatal("Negative size accepted")
+	}
+	if _, e := NewSparse(Matrix{{1, 0}, {1}}); e == nil {
+		t.Fatal("Ragged matrix accepted")
+	}
+	if s, e := NewSparse(Matrix{}); e != nil || s.NumRows() != 0 || s.NumCols() != 0 {
+		t.Fatalf("Empty matrix gave %v, %v", s, e)
+	}
+}